@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,12 +14,20 @@ import (
 	"github.com/captcha-platform/auth/internal/config"
 	"github.com/captcha-platform/auth/internal/database"
 	"github.com/captcha-platform/auth/internal/handlers"
+	"github.com/captcha-platform/auth/internal/metrics"
 	"github.com/captcha-platform/auth/internal/middleware"
 	"github.com/captcha-platform/auth/internal/repository"
 	"github.com/captcha-platform/auth/internal/services"
+	"github.com/captcha-platform/auth/internal/services/social"
+	"github.com/captcha-platform/auth/internal/shutdown"
+	"github.com/captcha-platform/auth/internal/telemetry"
+	"github.com/captcha-platform/auth/pkg/jwt"
 	"github.com/captcha-platform/auth/pkg/logger"
+	"github.com/captcha-platform/auth/pkg/mailer"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
@@ -38,36 +48,143 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Exports to cfg.Telemetry.OTLPEndpoint when configured; otherwise
+	// spans are still created and propagated (an inbound traceparent from
+	// the gateway is honored, and RequestID can adopt a trace ID) but
+	// never leave the process.
+	shutdownTracing := telemetry.Init("auth", cfg.Telemetry.OTLPEndpoint, log)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("Failed to shut down tracing", "error", err)
+		}
+	}()
+
 	// Initialize database connection
 	db, err := database.NewConnection(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to connect to database", "error", err)
 	}
-	defer db.Close()
+	// Closed explicitly as part of the shutdown drain below, after in-flight
+	// requests have finished, rather than deferred here.
 
 	log.Info("Connected to database successfully")
 
+	// Keeps metrics.DBPool* gauges current until the service shuts down.
+	dbStatsCtx, stopDBStatsCollector := context.WithCancel(context.Background())
+	defer stopDBStatsCollector()
+	metrics.StartDBPoolCollector(dbStatsCtx, db, 15*time.Second)
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	userLinkRepo := repository.NewUserLinkRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
+	oidcRepo := repository.NewOIDCRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+
+	// When configured for RS256, access tokens are signed with a rotating
+	// in-memory RSA keypair and verified via the /.well-known/jwks.json endpoint
+	// instead of the shared HS256 secret.
+	var keyManager *jwt.KeyManager
+	if cfg.JWT.SigningMethod == "RS256" {
+		keyManager, err = jwt.NewKeyManager(cfg.JWT.RSAKeyBits)
+		if err != nil {
+			log.Fatal("Failed to generate JWT signing key", "error", err)
+		}
+		go rotateSigningKey(keyManager, cfg.JWT.KeyRotationInterval, log)
+	}
+
+	// Redis backs login rate limiting and the token idle-timeout check; both
+	// degrade gracefully (limiter/idle check disabled) if it's unavailable.
+	redisClient, err := middleware.NewRedisClient(cfg.Redis)
+	if err != nil {
+		log.Warn("Failed to connect to Redis, login rate limiting and token idle timeout are disabled", "error", err)
+	}
+
+	// The validator also backs the revoked-jti blacklist and per-user
+	// revoke-all marker, so it's built whenever Redis is available even if
+	// idle-timeout enforcement itself is disabled.
+	var tokenValidator *jwt.Validator
+	if redisClient != nil {
+		tokenValidator = jwt.NewValidator(redisClient, cfg.Security.TokenIdleTimeout)
+	}
+
+	// The login attempt tracker also degrades gracefully with no Redis -
+	// Login just skips the progressive lockout check entirely.
+	loginAttemptTracker := services.NewLoginAttemptTracker(redisClient, cfg.Security.LoginLockoutWindow)
+	auditLogger := services.NewAuditLogger(auditRepo)
+
+	// Email verification and password-reset links only go out when an SMTP
+	// relay is configured; otherwise tokens are still issued and consumable,
+	// there's just nowhere to deliver them.
+	var mailSender mailer.Sender
+	if cfg.Email.SMTPHost != "" {
+		mailSender = mailer.NewSMTPSender(mailer.Config{
+			Host:     cfg.Email.SMTPHost,
+			Port:     cfg.Email.SMTPPort,
+			Username: cfg.Email.SMTPUsername,
+			Password: cfg.Email.SMTPPassword,
+			From:     cfg.Email.FromAddress,
+		})
+	} else {
+		log.Warn("SMTP_HOST not set, email verification and password-reset emails are disabled")
+	}
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, apiKeyRepo, cfg.JWT, cfg.BCrypt)
+	authService := services.NewAuthService(userRepo, apiKeyRepo, userLinkRepo, refreshTokenRepo, passwordResetRepo, emailVerificationRepo, cfg.JWT, keyManager, tokenValidator, cfg.OIDC, cfg.Security, cfg.BCrypt, cfg.APIKey, cfg.MFA, cfg.Email, mailSender, loginAttemptTracker, auditLogger)
+
+	// oidcProviderService is only functional when RS256 signing is
+	// configured (keyManager != nil) - ID tokens are always asymmetrically
+	// signed, see OIDCProviderService.
+	oidcProviderService := services.NewOIDCProviderService(oidcRepo, userRepo, keyManager, cfg.OP, cfg.BCrypt.Cost)
+
+	go sweepExpiredAPIKeys(apiKeyRepo, cfg.APIKey.ExpirySweepInterval, log)
+
+	// Social/upstream identity providers are resolved once at startup so a
+	// misconfigured provider (or one whose discovery document is
+	// unreachable) fails fast instead of on a user's first login attempt.
+	socialProviders, err := social.NewRegistry(context.Background(), cfg.OIDC)
+	if err != nil {
+		log.Fatal("Failed to initialize social login providers", "error", err)
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService, log)
+	authHandler := handlers.NewAuthHandler(authService, auditLogger, log)
+	oauthHandler := handlers.NewOAuthHandler(authService, cfg.OIDC, socialProviders, log)
+	oidcProviderHandler := handlers.NewOIDCProviderHandler(oidcProviderService, cfg.OP, log)
+	jwksHandler := handlers.NewJWKSHandler(keyManager)
 
 	// Setup Gin router
 	router := gin.New()
 
 	// Add middleware
 	router.Use(gin.Recovery())
+	// otelgin starts the request's span (and decodes an inbound
+	// traceparent header into it) before RequestID runs, so RequestID can
+	// adopt the trace ID; it runs before metrics.Middleware so the
+	// histogram observation covers the full request.
+	router.Use(otelgin.Middleware("auth"))
+	router.Use(metrics.Middleware())
 	router.Use(middleware.Logger(log))
 	router.Use(middleware.CORS(cfg.CORS))
 	router.Use(middleware.RequestID())
+	// Must run ahead of AuthRequired so it can mark an mTLS request from an
+	// allow-listed gateway SPIFFE ID as trusted before AuthRequired decides
+	// whether to honor its X-User-* headers.
+	router.Use(middleware.RequirePeerSPIFFE(cfg.ServiceAuth.AllowedGatewaySPIFFEIDs))
+
+	// readiness fails as soon as a shutdown signal is received, before the
+	// drain's pre-stop delay, so /readyz can deregister the pod from load
+	// balancers ahead of the HTTP server actually stopping.
+	readiness := shutdown.NewReadiness()
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
+	// Liveness - whether the process itself is up. Never fails during a
+	// graceful drain; k8s should only act on this to restart a wedged pod.
+	router.GET("/livez", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "healthy",
 			"service": "auth",
@@ -75,36 +192,127 @@ func main() {
 		})
 	})
 
+	// Readiness - whether this instance should receive new traffic.
+	router.GET("/readyz", func(c *gin.Context) {
+		if !readiness.OK() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "shutting_down",
+				"service": "auth",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ready",
+			"service": "auth",
+			"time":    time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	router.GET("/.well-known/jwks.json", jwksHandler.JWKS)
+	router.GET("/.well-known/openid-configuration", oidcProviderHandler.Discovery)
+
+	// This service's own OIDC provider (OP) endpoints, letting other
+	// services federate sign-in through it. Issuing tokens needs RS256
+	// signing: without it /authorize and /token return "unsupported",
+	// /jwks.json serves an empty key set and /introspect reports every
+	// token inactive.
+	oidc := router.Group("/oidc")
+	{
+		oidc.GET("/jwks.json", jwksHandler.JWKS)
+		oidc.GET("/authorize", middleware.AuthRequired(cfg.JWT, keyManager, tokenValidator), oidcProviderHandler.Authorize)
+		oidc.POST("/token", oidcProviderHandler.Token)
+		oidc.GET("/userinfo", middleware.AuthRequired(cfg.JWT, keyManager, tokenValidator), oidcProviderHandler.UserInfo)
+		oidc.POST("/introspect", oidcProviderHandler.Introspect)
+		oidc.POST("/clients", middleware.AuthRequired(cfg.JWT, keyManager, tokenValidator), middleware.AdminRequired(), oidcProviderHandler.RegisterClient)
+	}
+
 	// API routes
 	v1 := router.Group("/api/v1")
 	{
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			auth.POST("/register", middleware.PerIPRequestLimiter(redisClient, "register", cfg.Security.IPRateLimit), authHandler.Register)
+			auth.POST("/login",
+				middleware.PerIPRequestLimiter(redisClient, "login", cfg.Security.IPRateLimit),
+				middleware.LoginRateLimiter(redisClient, cfg.Security.AuthRateLimit),
+				authHandler.Login)
+			auth.POST("/login/mfa", middleware.MFAVerifyRateLimiter(redisClient, cfg.MFA.VerifyRateLimit), authHandler.LoginMFA)
 			auth.POST("/refresh", authHandler.RefreshToken)
 			auth.POST("/logout", authHandler.Logout)
+			auth.GET("/methods", oauthHandler.Methods)
+			auth.POST("/verify-email", middleware.PerIPRequestLimiter(redisClient, "verify-email", cfg.Security.IPRateLimit), authHandler.VerifyEmail)
+			auth.POST("/forgot-password",
+				middleware.PerIPRequestLimiter(redisClient, "forgot-password", cfg.Security.IPRateLimit),
+				middleware.PerEmailRequestLimiter(redisClient, "forgot-password", cfg.Security.EmailRateLimit),
+				authHandler.ForgotPassword)
+			auth.POST("/reset-password", middleware.PerIPRequestLimiter(redisClient, "reset-password", cfg.Security.IPRateLimit), authHandler.ResetPassword)
+
+			// OIDC/social login - public authorize/callback, but callback also
+			// honors an authenticated session so a user can link a provider.
+			oauth := auth.Group("/oauth/:provider")
+			{
+				oauth.GET("/authorize", oauthHandler.Authorize)
+				oauth.GET("/callback", middleware.OptionalAuth(cfg.JWT, keyManager, tokenValidator), oauthHandler.Callback)
+			}
 
 			// Protected routes
 			protected := auth.Group("")
-			protected.Use(middleware.AuthRequired(cfg.JWT.Secret))
+			protected.Use(middleware.AuthRequired(cfg.JWT, keyManager, tokenValidator))
 			{
 				protected.GET("/me", authHandler.GetCurrentUser)
 				protected.PUT("/me", authHandler.UpdateCurrentUser)
 				protected.PUT("/me/password", authHandler.ChangePassword)
+				protected.GET("/sessions", authHandler.GetMySessions)
+				protected.POST("/me/sessions/revoke-all", authHandler.RevokeMySessions)
+				protected.POST("/logout-all", authHandler.RevokeMySessions)
+				protected.POST("/me/mfa/enroll", authHandler.EnrollMFA)
+				protected.POST("/me/mfa/verify", authHandler.VerifyMFA)
+				protected.DELETE("/me/mfa", authHandler.DisableMFA)
+				protected.GET("/me/audit", authHandler.GetMyAuditLog)
+				protected.POST("/me/verify-email/request", authHandler.RequestEmailVerification)
 			}
 		}
 
 		// API Keys management (protected)
 		apiKeys := v1.Group("/api-keys")
-		apiKeys.Use(middleware.AuthRequired(cfg.JWT.Secret))
+		apiKeys.Use(middleware.AuthRequired(cfg.JWT, keyManager, tokenValidator))
 		{
 			apiKeys.GET("", authHandler.ListAPIKeys)
 			apiKeys.POST("", authHandler.CreateAPIKey)
 			apiKeys.DELETE("/:id", authHandler.DeleteAPIKey)
+			apiKeys.POST("/:id/rotate", authHandler.RotateAPIKey)
+			apiKeys.GET("/:id/usage", authHandler.GetAPIKeyUsage)
+		}
+
+		// Internal routes - not for end users, only trusted infrastructure
+		// (the gateway introspects API keys here to enforce scopes/rate limits
+		// and reports back batched usage so it isn't a DB write per request).
+		internalGroup := v1.Group("/internal")
+		{
+			internalGroup.GET("/api-keys/introspect", authHandler.IntrospectAPIKey)
+			internalGroup.POST("/api-keys/usage", authHandler.RecordAPIKeyUsage)
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthRequired(cfg.JWT, keyManager, tokenValidator), middleware.AdminRequired())
+		{
+			admin.POST("/users/:id/sessions/revoke-all", authHandler.RevokeUserSessions)
+			admin.DELETE("/users/:id/sessions", authHandler.RevokeUserSessions)
+			admin.GET("/audit", authHandler.GetAuditLog)
+			admin.GET("/users", authHandler.ListUsers)
+			admin.PATCH("/users/:id", authHandler.UpdateUserAdmin)
+			admin.DELETE("/users/:id", authHandler.DeleteUserAdmin)
+			admin.POST("/users/:id/reset-password", authHandler.ResetUserPassword)
 		}
 	}
 
+	// Tracks open connections so the shutdown drain can log how many remain
+	// once a second while it waits for them to close.
+	connTracker := &shutdown.ConnTracker{}
+
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -112,13 +320,34 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		ConnState:    connTracker.ConnState,
+	}
+
+	// TLS.ClientCAFile is what makes mTLS from the gateway possible -
+	// without it the server never asks for a client cert, so
+	// c.Request.TLS.PeerCertificates is empty and RequirePeerSPIFFE has
+	// nothing to check. TLS.RequireClientCert additionally rejects
+	// connections that present no cert at all.
+	if cfg.TLS.Enabled {
+		tlsConfig, err := buildTLSConfig(cfg.TLS)
+		if err != nil {
+			log.Fatal("Failed to configure TLS", "error", err)
+		}
+		srv.TLSConfig = tlsConfig
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Info("Auth Service started", "port", cfg.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Failed to start server", "error", err)
+		log.Info("Auth Service started", "port", cfg.Port, "tls", cfg.TLS.Enabled)
+
+		var serveErr error
+		if cfg.TLS.Enabled {
+			serveErr = srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			serveErr = srv.ListenAndServe()
+		}
+		if serveErr != nil && serveErr != http.ErrServerClosed {
+			log.Fatal("Failed to start server", "error", serveErr)
 		}
 	}()
 
@@ -129,14 +358,97 @@ func main() {
 
 	log.Info("Shutting down Auth Service...")
 
-	// Create a deadline to wait for
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	closers := []shutdown.Closer{
+		{Name: "database", Func: db.Close},
+	}
+	if redisClient != nil {
+		closers = append(closers, shutdown.Closer{Name: "redis", Func: redisClient.Close})
+	}
 
-	// Attempt graceful shutdown
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown", "error", err)
+	// A drain error just means the deadline hit before everything finished
+	// (or a closer failed) - log it and exit cleanly anyway, the process is
+	// going down either way and a Fatal here would itself abandon whatever
+	// the drain hadn't finished.
+	if err := shutdown.Drain(context.Background(), cfg.Shutdown.PreStopDelay, cfg.Shutdown.DrainTimeout, srv, connTracker, readiness, log, closers...); err != nil {
+		log.Error("Shutdown did not complete cleanly", "error", err)
 	}
 
 	log.Info("Auth Service stopped")
-}
\ No newline at end of file
+}
+
+// rotateSigningKey periodically rotates the RS256 signing key so a leaked
+// key has a bounded useful lifetime, pruning retired keys once no
+// outstanding access token could still reference them.
+func rotateSigningKey(km *jwt.KeyManager, interval time.Duration, log *logger.Logger) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := km.Rotate(); err != nil {
+			log.Error("Failed to rotate JWT signing key", "error", err)
+			continue
+		}
+		km.Prune(interval * 2)
+		log.Info("Rotated JWT signing key")
+	}
+}
+
+// sweepExpiredAPIKeys periodically deactivates API keys past their
+// ExpiresAt, so a caller presenting an expired key is rejected by the
+// is_active check rather than relying solely on the expiry check done at
+// validation time.
+func sweepExpiredAPIKeys(repo *repository.APIKeyRepository, interval time.Duration, log *logger.Logger) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := repo.SweepExpired(context.Background())
+		if err != nil {
+			log.Error("Failed to sweep expired API keys", "error", err)
+			continue
+		}
+		if count > 0 {
+			log.Info("Deactivated expired API keys", "count", count)
+		}
+	}
+}
+
+// buildTLSConfig sets up the server's TLS listener and, when a client CA
+// bundle is configured, enables mTLS by verifying client certificates
+// against it - RequirePeerSPIFFE then checks the verified peer's SPIFFE ID
+// against its own allow-list before trusting any gateway-set identity
+// headers.
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caCert, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse client CA file %q", cfg.ClientCAFile)
+	}
+
+	tlsConfig.ClientCAs = caPool
+	if cfg.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
@@ -19,6 +19,132 @@ type Config struct {
 	BCrypt      BCryptConfig
 	CORS        CORSConfig
 	Log         LogConfig
+	OIDC        OIDCConfig
+	Redis       RedisConfig
+	Security    SecurityConfig
+	APIKey      APIKeyConfig
+	MFA         MFAConfig
+	OP          OPConfig
+	Telemetry   TelemetryConfig
+	Shutdown    ShutdownConfig
+	TLS         TLSConfig
+	ServiceAuth ServiceAuthConfig
+	Email       EmailConfig
+}
+
+// TLSConfig controls whether the auth service terminates TLS itself, and,
+// when mTLS is enabled, which client certificates it accepts. ClientCAFile
+// is what makes mTLS possible here - it's the bundle the gateway's upstream
+// client certificate must chain to (see middleware.RequirePeerSPIFFE, which
+// checks the verified peer cert's SPIFFE ID against ServiceAuthConfig's
+// allow-list).
+type TLSConfig struct {
+	Enabled           bool
+	CertFile          string
+	KeyFile           string
+	ClientCAFile      string
+	RequireClientCert bool
+}
+
+// ServiceAuthConfig lists the peer identities this service trusts as "the
+// gateway" over an mTLS connection. middleware.RequirePeerSPIFFE only marks
+// a request as gateway-trusted (letting AuthRequired honor its X-User-*
+// headers instead of requiring its own JWT) when the verified peer
+// certificate's SPIFFE ID is in this list.
+type ServiceAuthConfig struct {
+	AllowedGatewaySPIFFEIDs []string
+}
+
+// ShutdownConfig controls the graceful-drain sequence run on SIGINT/SIGTERM.
+// /readyz starts failing immediately; PreStopDelay then gives upstream load
+// balancers time to deregister the pod before the HTTP server stops
+// accepting new connections, and DrainTimeout bounds how long in-flight
+// requests get to finish. Background workers are closed after that, with
+// no deadline of their own.
+type ShutdownConfig struct {
+	PreStopDelay time.Duration
+	DrainTimeout time.Duration
+}
+
+// TelemetryConfig controls OpenTelemetry trace export. OTLPEndpoint empty
+// means tracing stays local (spans are created and propagated but never
+// exported) - see telemetry.Init.
+type TelemetryConfig struct {
+	OTLPEndpoint string
+}
+
+// OPConfig holds settings for this service's own OIDC provider (OP) mode,
+// which lets other services federate sign-in through it. It only takes
+// effect when JWT.SigningMethod is "RS256", since ID tokens are always
+// asymmetrically signed.
+type OPConfig struct {
+	Issuer           string        // this service's issuer URL, used in the discovery document and ID tokens
+	AuthCodeTTL      time.Duration // how long an authorization code from GET /oidc/authorize stays valid
+	IDTokenExpiresIn time.Duration // lifetime of ID tokens and the access tokens issued alongside them
+	RefreshTokenTTL  time.Duration // lifetime of refresh tokens issued by the authorization_code grant
+}
+
+// MFAConfig holds TOTP-based multi-factor authentication settings.
+type MFAConfig struct {
+	Issuer            string              // shown in the authenticator app next to the account
+	PendingTokenTTL   time.Duration       // how long a Login's mfa_pending_token stays valid
+	RecoveryCodeCount int                 // how many one-time recovery codes EnrollMFA issues
+	VerifyRateLimit   AuthRateLimitConfig // caps failed /auth/login/mfa attempts, keyed by pending token
+}
+
+// APIKeyConfig holds API-key lifecycle settings: how long a rotated key's
+// previous secret keeps working, and how often expired keys are swept.
+type APIKeyConfig struct {
+	RotationGracePeriod time.Duration // how long the previous secret validates after a rotation; 0 disables the grace period
+	ExpirySweepInterval time.Duration // how often the expiry sweeper runs; 0 disables it
+}
+
+// RedisConfig holds Redis connection settings, used for login rate limiting
+// and token idle-timeout tracking.
+type RedisConfig struct {
+	Host     string
+	Port     int
+	Password string
+	DB       int
+}
+
+// Addr returns the Redis server address
+func (r RedisConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", r.Host, r.Port)
+}
+
+// SecurityConfig holds settings for brute-force protection, idle token
+// expiry, and concurrent-session policy.
+type SecurityConfig struct {
+	AuthRateLimit      AuthRateLimitConfig
+	IPRateLimit        AuthRateLimitConfig // blunt per-IP request-volume cap (see middleware.PerIPRequestLimiter), independent of AuthRateLimit's failure-only count
+	EmailRateLimit     AuthRateLimitConfig // blunt per-email request-volume cap (see middleware.PerEmailRequestLimiter) for endpoints like /auth/forgot-password that take an email but no password to fail on
+	TokenIdleTimeout   time.Duration       // a token is rejected if unused for this long, even if not yet expired; 0 disables
+	EnableMultiLogin   bool                // if false, a successful login revokes every other active session for that user
+	LoginLockoutWindow time.Duration       // how long a failed login attempt counts toward services.LoginAttemptTracker's escalating thresholds
+}
+
+// AuthRateLimitConfig caps failed login attempts in a sliding window, keyed
+// on (email, IP), e.g. 5 attempts per 30 minutes.
+type AuthRateLimitConfig struct {
+	MaxAttempts int
+	Window      time.Duration
+}
+
+// OIDCConfig holds settings for one or more OpenID Connect providers used for
+// social login. Providers are keyed by login_type (e.g. "google", "github").
+type OIDCConfig struct {
+	Providers           map[string]OIDCProviderConfig
+	RequireExplicitLink bool // if true, a password user must explicitly link before OIDC can authenticate them
+}
+
+// OIDCProviderConfig holds settings for a single OIDC provider
+type OIDCProviderConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
 // DatabaseConfig holds database connection settings
@@ -34,10 +160,13 @@ type DatabaseConfig struct {
 
 // JWTConfig holds JWT settings
 type JWTConfig struct {
-	Secret           string
-	ExpiresIn        time.Duration
-	RefreshExpiresIn time.Duration
-	Issuer           string
+	Secret              string
+	ExpiresIn           time.Duration
+	RefreshExpiresIn    time.Duration
+	Issuer              string
+	SigningMethod       string        // "HS256" (default) or "RS256"
+	RSAKeyBits          int           // key size used when generating RS256 signing keys
+	KeyRotationInterval time.Duration // how often to rotate the RS256 signing key; 0 disables rotation
 }
 
 // BCryptConfig holds password hashing settings
@@ -58,6 +187,23 @@ type LogConfig struct {
 	Format string
 }
 
+// EmailConfig holds SMTP relay settings for account-verification and
+// password-reset email, plus the link bases those emails point at (the
+// frontend route that collects the token from the URL and calls back into
+// the API). SMTPHost empty disables outbound email entirely - tokens are
+// still issued and consumable, there's just nothing to deliver them.
+type EmailConfig struct {
+	SMTPHost             string
+	SMTPPort             int
+	SMTPUsername         string
+	SMTPPassword         string
+	FromAddress          string
+	VerificationURLBase  string
+	PasswordResetURLBase string
+	VerificationTTL      time.Duration
+	PasswordResetTTL     time.Duration
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if exists (for development)
@@ -76,10 +222,13 @@ func Load() (*Config, error) {
 			Timeout:  time.Duration(getEnvInt("DB_TIMEOUT_SECONDS", 30)) * time.Second,
 		},
 		JWT: JWTConfig{
-			Secret:           getEnvString("JWT_SECRET", ""),
-			ExpiresIn:        parseDuration(getEnvString("JWT_EXPIRES_IN", "24h")),
-			RefreshExpiresIn: parseDuration(getEnvString("JWT_REFRESH_EXPIRES_IN", "7d")),
-			Issuer:           getEnvString("JWT_ISSUER", "captcha-platform"),
+			Secret:              getEnvString("JWT_SECRET", ""),
+			ExpiresIn:           parseDuration(getEnvString("JWT_EXPIRES_IN", "24h")),
+			RefreshExpiresIn:    parseDuration(getEnvString("JWT_REFRESH_EXPIRES_IN", "7d")),
+			Issuer:              getEnvString("JWT_ISSUER", "captcha-platform"),
+			SigningMethod:       strings.ToUpper(getEnvString("JWT_SIGNING_METHOD", "HS256")),
+			RSAKeyBits:          getEnvInt("JWT_RSA_KEY_BITS", 2048),
+			KeyRotationInterval: parseDuration(getEnvString("JWT_KEY_ROTATION_INTERVAL", "24h")),
 		},
 		BCrypt: BCryptConfig{
 			Cost: getEnvInt("BCRYPT_COST", 12),
@@ -93,24 +242,106 @@ func Load() (*Config, error) {
 			Level:  getEnvString("LOG_LEVEL", "debug"),
 			Format: getEnvString("LOG_FORMAT", "json"),
 		},
+		OIDC: loadOIDCConfig(),
+		Redis: RedisConfig{
+			Host:     getEnvString("REDIS_HOST", "localhost"),
+			Port:     getEnvInt("REDIS_PORT", 6379),
+			Password: getEnvString("REDIS_PASSWORD", ""),
+			DB:       getEnvInt("REDIS_DB", 0),
+		},
+		Security: SecurityConfig{
+			AuthRateLimit:      parseAuthRateLimit(getEnvString("AUTH_RATE_LIMIT", "5/30m")),
+			IPRateLimit:        parseAuthRateLimit(getEnvString("IP_RATE_LIMIT", "10/1m")),
+			EmailRateLimit:     parseAuthRateLimit(getEnvString("EMAIL_RATE_LIMIT", "3/15m")),
+			TokenIdleTimeout:   parseDuration(getEnvString("TOKEN_IDLE_TIMEOUT", "0")),
+			EnableMultiLogin:   getEnvBool("ENABLE_MULTI_LOGIN", true),
+			LoginLockoutWindow: parseDuration(getEnvString("LOGIN_LOCKOUT_WINDOW", "24h")),
+		},
+		APIKey: APIKeyConfig{
+			RotationGracePeriod: parseDuration(getEnvString("API_KEY_ROTATION_GRACE_PERIOD", "24h")),
+			ExpirySweepInterval: parseDuration(getEnvString("API_KEY_EXPIRY_SWEEP_INTERVAL", "5m")),
+		},
+		MFA: MFAConfig{
+			Issuer:            getEnvString("MFA_ISSUER", "captcha-platform"),
+			PendingTokenTTL:   parseDuration(getEnvString("MFA_PENDING_TOKEN_TTL", "5m")),
+			RecoveryCodeCount: getEnvInt("MFA_RECOVERY_CODE_COUNT", 10),
+			VerifyRateLimit:   parseAuthRateLimit(getEnvString("MFA_VERIFY_RATE_LIMIT", "5/5m")),
+		},
+		OP: OPConfig{
+			Issuer:           getEnvString("OIDC_PROVIDER_ISSUER", "http://localhost:8081"),
+			AuthCodeTTL:      parseDuration(getEnvString("OIDC_PROVIDER_AUTH_CODE_TTL", "1m")),
+			IDTokenExpiresIn: parseDuration(getEnvString("OIDC_PROVIDER_ID_TOKEN_EXPIRES_IN", "1h")),
+			RefreshTokenTTL:  parseDuration(getEnvString("OIDC_PROVIDER_REFRESH_TOKEN_TTL", "720h")),
+		},
+		Telemetry: TelemetryConfig{
+			OTLPEndpoint: getEnvString("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		},
+		Shutdown: ShutdownConfig{
+			PreStopDelay: parseDuration(getEnvString("SHUTDOWN_PRE_STOP_DELAY", "5s")),
+			DrainTimeout: parseDuration(getEnvString("SHUTDOWN_DRAIN_TIMEOUT", "30s")),
+		},
+		TLS: TLSConfig{
+			Enabled:           getEnvBool("TLS_ENABLED", false),
+			CertFile:          getEnvString("TLS_CERT_FILE", ""),
+			KeyFile:           getEnvString("TLS_KEY_FILE", ""),
+			ClientCAFile:      getEnvString("TLS_CLIENT_CA_FILE", ""),
+			RequireClientCert: getEnvBool("TLS_REQUIRE_CLIENT_CERT", false),
+		},
+		ServiceAuth: ServiceAuthConfig{
+			AllowedGatewaySPIFFEIDs: splitNonEmpty(getEnvString("ALLOWED_GATEWAY_SPIFFE_IDS", "")),
+		},
+		Email: EmailConfig{
+			SMTPHost:             getEnvString("SMTP_HOST", ""),
+			SMTPPort:             getEnvInt("SMTP_PORT", 587),
+			SMTPUsername:         getEnvString("SMTP_USERNAME", ""),
+			SMTPPassword:         getEnvString("SMTP_PASSWORD", ""),
+			FromAddress:          getEnvString("SMTP_FROM_ADDRESS", "no-reply@captcha-platform.local"),
+			VerificationURLBase:  getEnvString("EMAIL_VERIFICATION_URL_BASE", "http://localhost:3000/verify-email"),
+			PasswordResetURLBase: getEnvString("PASSWORD_RESET_URL_BASE", "http://localhost:3000/reset-password"),
+			VerificationTTL:      parseDuration(getEnvString("EMAIL_VERIFICATION_TTL", "24h")),
+			PasswordResetTTL:     parseDuration(getEnvString("PASSWORD_RESET_TTL", "1h")),
+		},
 	}
 
 	// Validate required fields
-	if cfg.JWT.Secret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is required")
-	}
+	if cfg.JWT.SigningMethod != "RS256" {
+		if cfg.JWT.Secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required")
+		}
 
-	if len(cfg.JWT.Secret) < 32 {
-		return nil, fmt.Errorf("JWT_SECRET must be at least 32 characters long")
+		if len(cfg.JWT.Secret) < 32 {
+			return nil, fmt.Errorf("JWT_SECRET must be at least 32 characters long")
+		}
 	}
 
 	if cfg.Database.Password == "" {
 		return nil, fmt.Errorf("DB_PASSWORD is required")
 	}
 
+	if cfg.TLS.Enabled && (cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE are required when TLS_ENABLED is true")
+	}
+
 	return cfg, nil
 }
 
+// splitNonEmpty splits a comma-separated list, dropping empty entries, so a
+// trailing comma or an unset env var yields an empty slice rather than a
+// slice containing "".
+func splitNonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 // DSN returns the database connection string
 func (d *DatabaseConfig) DSN() string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=UTC&timeout=%s",
@@ -155,4 +386,61 @@ func parseDuration(value string) time.Duration {
 		return 24 * time.Hour // Default to 24 hours
 	}
 	return duration
-}
\ No newline at end of file
+}
+
+// loadOIDCConfig loads one or more OIDC provider configurations.
+// A provider is considered configured (and enabled) once its client ID and
+// secret are both set, e.g. OIDC_GOOGLE_CLIENT_ID / OIDC_GOOGLE_CLIENT_SECRET.
+func loadOIDCConfig() OIDCConfig {
+	cfg := OIDCConfig{
+		Providers:           make(map[string]OIDCProviderConfig),
+		RequireExplicitLink: getEnvBool("OIDC_REQUIRE_EXPLICIT_LINK", true),
+	}
+
+	for _, name := range []string{"google", "github", "oidc"} {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		clientID := getEnvString(prefix+"CLIENT_ID", "")
+		clientSecret := getEnvString(prefix+"CLIENT_SECRET", "")
+		if clientID == "" || clientSecret == "" {
+			continue
+		}
+
+		cfg.Providers[name] = OIDCProviderConfig{
+			IssuerURL:    getEnvString(prefix+"ISSUER_URL", ""),
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  getEnvString(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Split(getEnvString(prefix+"SCOPES", "openid,email,profile"), ","),
+		}
+	}
+
+	return cfg
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// parseAuthRateLimit parses strings like "5/30m" (5 attempts per 30 minutes).
+// An unparseable value disables the limiter (zero MaxAttempts).
+func parseAuthRateLimit(value string) AuthRateLimitConfig {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return AuthRateLimitConfig{}
+	}
+
+	attempts, err := strconv.Atoi(parts[0])
+	if err != nil || attempts <= 0 {
+		return AuthRateLimitConfig{}
+	}
+
+	return AuthRateLimitConfig{
+		MaxAttempts: attempts,
+		Window:      parseDuration(parts[1]),
+	}
+}
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/captcha-platform/auth/internal/middleware"
 	"github.com/captcha-platform/auth/internal/models"
 	"github.com/captcha-platform/auth/internal/repository"
 	"github.com/captcha-platform/auth/internal/services"
+	"github.com/captcha-platform/auth/pkg/jwt"
 	"github.com/captcha-platform/auth/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -15,13 +21,15 @@ import (
 // AuthHandler handles authentication HTTP requests
 type AuthHandler struct {
 	authService *services.AuthService
+	auditLogger *services.AuditLogger
 	logger      *logger.Logger
 }
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(authService *services.AuthService, logger *logger.Logger) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, auditLogger *services.AuditLogger, logger *logger.Logger) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		auditLogger: auditLogger,
 		logger:      logger,
 	}
 }
@@ -92,15 +100,22 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(c.Request.Context(), &req)
+	response, err := h.authService.Login(c.Request.Context(), &req, c.Request.UserAgent(), middleware.ClientIP(c), c.GetString("request_id"))
 	if err != nil {
-		switch err {
-		case services.ErrInvalidCredentials:
+		var lockedErr *services.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(lockedErr.RetryAfter.Seconds())))
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{
+				Error:   "too_many_attempts",
+				Message: "Too many failed login attempts, please try again later",
+			})
+		case err == services.ErrInvalidCredentials:
 			c.JSON(http.StatusUnauthorized, ErrorResponse{
 				Error:   "invalid_credentials",
 				Message: "Invalid email or password",
 			})
-		case services.ErrUserNotActive:
+		case err == services.ErrUserNotActive:
 			c.JSON(http.StatusForbidden, ErrorResponse{
 				Error:   "user_inactive",
 				Message: "User account is not active",
@@ -115,10 +130,65 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if response.MFARequired {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	h.logger.Info("User logged in", "user_id", response.User.ID)
 	c.JSON(http.StatusOK, response)
 }
 
+// LoginMFA completes a login that returned an MFA challenge, submitting the
+// pending token plus a live TOTP (or recovery) code.
+// @Summary Complete MFA login
+// @Description Submit the mfa_pending_token from Login plus a TOTP or recovery code to receive tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.LoginMFARequest true "MFA login request"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req models.LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.VerifyLoginMFA(c.Request.Context(), &req, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		switch err {
+		case services.ErrInvalidMFACode, services.ErrInvalidToken, services.ErrMFANotEnabled:
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "invalid_mfa_code",
+				Message: "Invalid or expired MFA code",
+			})
+		case services.ErrUserNotActive:
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Error:   "user_inactive",
+				Message: "User account is not active",
+			})
+		default:
+			h.logger.Error("Failed to complete MFA login", "error", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "internal_error",
+				Message: "Failed to authenticate",
+			})
+		}
+		return
+	}
+
+	h.logger.Info("User completed MFA login", "user_id", response.User.ID)
+	c.JSON(http.StatusOK, response)
+}
+
 // RefreshToken handles token refresh
 // @Summary Refresh access token
 // @Description Get a new access token using a refresh token
@@ -141,7 +211,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	response, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken, c.Request.UserAgent(), middleware.ClientIP(c), c.GetString("request_id"))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Error:   "invalid_token",
@@ -155,17 +225,32 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 // Logout handles user logout
 // @Summary Logout user
-// @Description Invalidate user tokens (client-side token removal)
+// @Description Revoke the presented access token and, if provided, the
+// @Description refresh-token session tied to it, so /auth/refresh can't
+// @Description mint a new access token afterward.
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Security BearerAuth
+// @Param request body models.RefreshTokenRequest false "Refresh token to revoke along with the current session"
 // @Success 200 {object} SuccessResponse
 // @Router /auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// In a stateless JWT setup, logout is handled client-side
-	// For a more secure implementation, you could:
-	// 1. Add the token to a blacklist in Redis
-	// 2. Use short-lived access tokens with refresh token rotation
+	if token, err := jwt.ExtractTokenFromHeader(c.GetHeader("Authorization")); err == nil {
+		if err := h.authService.RevokeToken(c.Request.Context(), token); err != nil {
+			h.logger.Error("Failed to revoke token on logout", "error", err)
+		}
+	}
+
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = c.ShouldBindJSON(&body)
+	if body.RefreshToken != "" {
+		if err := h.authService.RevokeSession(c.Request.Context(), body.RefreshToken); err != nil {
+			h.logger.Error("Failed to revoke session on logout", "error", err)
+		}
+	}
 
 	c.JSON(http.StatusOK, SuccessResponse{
 		Message: "Successfully logged out",
@@ -288,7 +373,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.ChangePassword(c.Request.Context(), userID.(uint64), &req)
+	err := h.authService.ChangePassword(c.Request.Context(), userID.(uint64), &req, middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id"))
 	if err != nil {
 		if err == services.ErrInvalidCredentials {
 			c.JSON(http.StatusBadRequest, ErrorResponse{
@@ -310,17 +395,172 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	})
 }
 
-// ListAPIKeys lists all API keys for the current user
-// @Summary List API keys
-// @Description Get all API keys for the authenticated user
-// @Tags api-keys
+// RequestEmailVerification sends the current user a fresh email-verification
+// link.
+// @Summary Request email verification
+// @Description Emails the current user a link to confirm their address
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/me/verify-email/request [post]
+func (h *AuthHandler) RequestEmailVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	if err := h.authService.RequestEmailVerification(c.Request.Context(), userID.(uint64)); err != nil {
+		h.logger.Error("Failed to request email verification", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to send verification email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Message: "Verification email sent if the account isn't already verified",
+	})
+}
+
+// VerifyEmail confirms an email address using the token from the link sent
+// by RequestEmailVerification.
+// @Summary Verify email address
+// @Description Confirms a user's email using a one-time token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		if err == services.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_token",
+				Message: "Verification link is invalid or has expired",
+			})
+			return
+		}
+		h.logger.Error("Failed to verify email", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to verify email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Email verified"})
+}
+
+// ForgotPassword starts the password-reset flow for an email address.
+// @Summary Request a password reset
+// @Description Emails a password-reset link if the address belongs to an account
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email, middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id")); err != nil {
+		h.logger.Error("Failed to process forgot-password request", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to process request",
+		})
+		return
+	}
+
+	// Always the same response, whether or not the email matched an
+	// account, so this endpoint can't be used to enumerate users.
+	c.JSON(http.StatusOK, SuccessResponse{
+		Message: "If that email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword completes the password-reset flow using a token from the
+// link sent by ForgotPassword.
+// @Summary Reset password with a token
+// @Description Sets a new password using a one-time reset token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword, middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id")); err != nil {
+		if err == services.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_token",
+				Message: "Reset link is invalid or has expired",
+			})
+			return
+		}
+		h.logger.Error("Failed to reset password", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to reset password",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Password reset successfully"})
+}
+
+// EnrollMFA begins TOTP enrollment for the current user, returning a fresh
+// secret, otpauth:// URI, a scannable QR code, and one-time recovery codes.
+// MFA isn't active until the secret is confirmed via VerifyMFA.
+// @Summary Enroll in MFA
+// @Description Generate a TOTP secret, QR code, and recovery codes for the current user
+// @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} models.APIKeyResponse
+// @Success 200 {object} models.MFAEnrollResponse
 // @Failure 401 {object} ErrorResponse
-// @Router /api-keys [get]
-func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+// @Router /auth/me/mfa/enroll [post]
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
@@ -330,32 +570,33 @@ func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
 		return
 	}
 
-	apiKeys, err := h.authService.ListAPIKeys(c.Request.Context(), userID.(uint64))
+	resp, err := h.authService.EnrollMFA(c.Request.Context(), userID.(uint64), middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id"))
 	if err != nil {
-		h.logger.Error("Failed to list API keys", "error", err)
+		h.logger.Error("Failed to enroll MFA", "error", err, "user_id", userID)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "internal_error",
-			Message: "Failed to list API keys",
+			Message: "Failed to start MFA enrollment",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, apiKeys)
+	c.JSON(http.StatusOK, resp)
 }
 
-// CreateAPIKey creates a new API key for the current user
-// @Summary Create API key
-// @Description Create a new API key for the authenticated user
-// @Tags api-keys
+// VerifyMFA activates MFA after confirming a live TOTP code from the
+// secret EnrollMFA issued.
+// @Summary Verify and activate MFA
+// @Description Confirm a TOTP code from the enrolled secret to turn MFA on
+// @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param request body models.CreateAPIKeyRequest true "API key creation request"
-// @Success 201 {object} models.APIKeyWithSecret
+// @Param request body models.MFAVerifyRequest true "MFA verify request"
+// @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
-// @Router /api-keys [post]
-func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+// @Router /auth/me/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
@@ -365,7 +606,7 @@ func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
 		return
 	}
 
-	var req models.CreateAPIKeyRequest
+	var req models.MFAVerifyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error:   "validation_error",
@@ -375,40 +616,42 @@ func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
 		return
 	}
 
-	apiKey, err := h.authService.CreateAPIKey(c.Request.Context(), userID.(uint64), &req)
-	if err != nil {
-		if err == services.ErrMaxAPIKeysReached {
+	if err := h.authService.VerifyMFA(c.Request.Context(), userID.(uint64), req.Code); err != nil {
+		if err == services.ErrInvalidMFACode || err == services.ErrMFANotPending {
 			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error:   "limit_exceeded",
-				Message: "Maximum number of API keys reached",
+				Error:   "invalid_code",
+				Message: "Invalid or expired MFA code",
 			})
 			return
 		}
-		h.logger.Error("Failed to create API key", "error", err)
+		h.logger.Error("Failed to verify MFA", "error", err, "user_id", userID)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "internal_error",
-			Message: "Failed to create API key",
+			Message: "Failed to verify MFA",
 		})
 		return
 	}
 
-	h.logger.Info("API key created", "user_id", userID, "key_id", apiKey.ID)
-	c.JSON(http.StatusCreated, apiKey)
+	h.logger.Info("MFA enabled", "user_id", userID)
+	c.JSON(http.StatusOK, SuccessResponse{
+		Message: "MFA enabled",
+	})
 }
 
-// DeleteAPIKey deletes an API key
-// @Summary Delete API key
-// @Description Delete an API key by ID
-// @Tags api-keys
+// DisableMFA turns MFA off for the current user after re-checking their
+// password.
+// @Summary Disable MFA
+// @Description Turn MFA off, re-confirming the account password first
+// @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "API key ID"
+// @Param request body models.MFADisableRequest true "MFA disable request"
 // @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Router /api-keys/{id} [delete]
-func (h *AuthHandler) DeleteAPIKey(c *gin.Context) {
+// @Router /auth/me/mfa [delete]
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
@@ -418,47 +661,862 @@ func (h *AuthHandler) DeleteAPIKey(c *gin.Context) {
 		return
 	}
 
-	keyIDStr := c.Param("id")
-	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
-	if err != nil {
+	var req models.MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "invalid_id",
-			Message: "Invalid API key ID",
+			Error:   "validation_error",
+			Message: "Invalid request body",
+			Details: err.Error(),
 		})
 		return
 	}
 
-	err = h.authService.DeleteAPIKey(c.Request.Context(), userID.(uint64), keyID)
-	if err != nil {
-		if err == repository.ErrAPIKeyNotFound {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error:   "not_found",
-				Message: "API key not found",
+	if err := h.authService.DisableMFA(c.Request.Context(), userID.(uint64), req.Password); err != nil {
+		if err == services.ErrInvalidCredentials {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_password",
+				Message: "Current password is incorrect",
 			})
 			return
 		}
-		h.logger.Error("Failed to delete API key", "error", err)
+		h.logger.Error("Failed to disable MFA", "error", err, "user_id", userID)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "internal_error",
-			Message: "Failed to delete API key",
+			Message: "Failed to disable MFA",
 		})
 		return
 	}
 
-	h.logger.Info("API key deleted", "user_id", userID, "key_id", keyID)
+	h.logger.Info("MFA disabled", "user_id", userID)
 	c.JSON(http.StatusOK, SuccessResponse{
-		Message: "API key deleted successfully",
+		Message: "MFA disabled",
 	})
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+// ListAPIKeys lists all API keys for the current user
+// @Summary List API keys
+// @Description Get all API keys for the authenticated user
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKeyResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api-keys [get]
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	apiKeys, err := h.authService.ListAPIKeys(c.Request.Context(), userID.(uint64))
+	if err != nil {
+		h.logger.Error("Failed to list API keys", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to list API keys",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
 }
 
-// SuccessResponse represents a success response
-type SuccessResponse struct {
-	Message string `json:"message"`
-}
\ No newline at end of file
+// CreateAPIKey creates a new API key for the current user
+// @Summary Create API key
+// @Description Create a new API key for the authenticated user
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateAPIKeyRequest true "API key creation request"
+// @Success 201 {object} models.APIKeyWithSecret
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api-keys [post]
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	apiKey, err := h.authService.CreateAPIKey(c.Request.Context(), userID.(uint64), &req, middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id"))
+	if err != nil {
+		if err == services.ErrMaxAPIKeysReached {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "limit_exceeded",
+				Message: "Maximum number of API keys reached",
+			})
+			return
+		}
+		if err == services.ErrInvalidScope {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_scope",
+				Message: "One or more requested scopes are not recognized",
+			})
+			return
+		}
+		h.logger.Error("Failed to create API key", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to create API key",
+		})
+		return
+	}
+
+	h.logger.Info("API key created", "user_id", userID, "key_id", apiKey.ID)
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// DeleteAPIKey deletes an API key
+// @Summary Delete API key
+// @Description Delete an API key by ID
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id} [delete]
+func (h *AuthHandler) DeleteAPIKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid API key ID",
+		})
+		return
+	}
+
+	err = h.authService.DeleteAPIKey(c.Request.Context(), userID.(uint64), keyID, middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id"))
+	if err != nil {
+		if err == repository.ErrAPIKeyNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "not_found",
+				Message: "API key not found",
+			})
+			return
+		}
+		h.logger.Error("Failed to delete API key", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to delete API key",
+		})
+		return
+	}
+
+	h.logger.Info("API key deleted", "user_id", userID, "key_id", keyID)
+	c.JSON(http.StatusOK, SuccessResponse{
+		Message: "API key deleted successfully",
+	})
+}
+
+// RevokeMySessions lets the current user log themselves out of every
+// device, revoking every refresh token and access token they've been
+// issued.
+// @Summary Revoke all of my sessions
+// @Description Logout-everywhere for the currently authenticated user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/me/sessions/revoke-all [post]
+func (h *AuthHandler) RevokeMySessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeAllSessions(c.Request.Context(), userID.(uint64)); err != nil {
+		h.logger.Error("Failed to revoke own sessions", "error", err, "user_id", userID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Message: "All sessions revoked",
+	})
+}
+
+// GetMySessions lists the current user's active sessions (refresh tokens),
+// with the device metadata recorded when each was issued, so a user can spot
+// a device they don't recognize before revoking it.
+// @Summary List my active sessions
+// @Description Lists active refresh-token sessions with device metadata
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string][]models.SessionResponse "sessions"
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/sessions [get]
+func (h *AuthHandler) GetMySessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID.(uint64))
+	if err != nil {
+		h.logger.Error("Failed to list sessions", "error", err, "user_id", userID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to list sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeUserSessions revokes all of a user's refresh tokens and access
+// tokens, logging them out everywhere. Registered under both the original
+// POST route and the DELETE /admin/users/:id/sessions route.
+// @Summary Revoke all sessions for a user
+// @Description Admin logout-everywhere: revokes every refresh token and access token belonging to the user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /admin/users/{id}/sessions/revoke-all [post]
+// @Router /admin/users/{id}/sessions [delete]
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid user ID",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeAllSessions(c.Request.Context(), userID); err != nil {
+		h.logger.Error("Failed to revoke sessions", "error", err, "user_id", userID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to revoke sessions",
+		})
+		return
+	}
+
+	h.logger.Info("Revoked all sessions for user", "user_id", userID)
+	c.JSON(http.StatusOK, SuccessResponse{
+		Message: "All sessions revoked",
+	})
+}
+
+// IntrospectAPIKey validates an API key for internal service-to-service use.
+// The gateway calls this to resolve a raw API key's scopes and per-key rate
+// limit before proxying a request upstream; it is only meant to be reachable
+// from trusted infrastructure, not end users.
+// @Summary Introspect an API key
+// @Description Internal endpoint: validates an API key and returns its scopes and rate limit
+// @Tags internal
+// @Produce json
+// @Success 200 {object} models.APIKeyIntrospectResponse
+// @Router /internal/api-keys/introspect [get]
+func (h *AuthHandler) IntrospectAPIKey(c *gin.Context) {
+	key := c.GetHeader("X-API-Key")
+	if key == "" {
+		c.JSON(http.StatusOK, models.APIKeyIntrospectResponse{Valid: false})
+		return
+	}
+
+	_, apiKey, err := h.authService.ValidateAPIKey(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusOK, models.APIKeyIntrospectResponse{Valid: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIKeyIntrospectResponse{
+		Valid:     true,
+		UserID:    apiKey.UserID,
+		KeyID:     apiKey.ID,
+		KeyPrefix: apiKey.KeyPrefix,
+		Scopes:    apiKey.ScopeList(),
+		RateLimit: apiKey.RateLimit,
+	})
+}
+
+// RecordAPIKeyUsage applies a batch of usage increments the gateway has
+// accumulated since its last flush. Internal endpoint, same trust boundary
+// as IntrospectAPIKey.
+// @Summary Record batched API key usage
+// @Description Internal endpoint: applies batched total_requests/last_used_at increments
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /internal/api-keys/usage [post]
+func (h *AuthHandler) RecordAPIKeyUsage(c *gin.Context) {
+	var increments []models.APIKeyUsageIncrement
+	if err := c.ShouldBindJSON(&increments); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.RecordAPIKeyUsage(c.Request.Context(), increments); err != nil {
+		h.logger.Error("Failed to record API key usage", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to record API key usage",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RotateAPIKey issues a new secret for an existing API key, preserving its
+// ID, name, and scopes. The previous secret keeps validating for a grace
+// period so in-flight callers have time to switch over.
+// @Summary Rotate an API key
+// @Description Issue a new secret for an API key, keeping the old one valid for a grace period
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIKeyWithSecret
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id}/rotate [post]
+func (h *AuthHandler) RotateAPIKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid API key ID",
+		})
+		return
+	}
+
+	rotated, err := h.authService.RotateAPIKey(c.Request.Context(), userID.(uint64), keyID)
+	if err != nil {
+		if err == repository.ErrAPIKeyNotFound || err == services.ErrAPIKeyNotOwned {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "not_found",
+				Message: "API key not found",
+			})
+			return
+		}
+		h.logger.Error("Failed to rotate API key", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to rotate API key",
+		})
+		return
+	}
+
+	h.logger.Info("API key rotated", "user_id", userID, "key_id", keyID)
+	c.JSON(http.StatusOK, rotated)
+}
+
+// GetAPIKeyUsage returns hourly request-count buckets for an API key over
+// the last 7 days.
+// @Summary Get API key usage
+// @Description Time-bucketed request counts for an API key
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIKeyUsageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id}/usage [get]
+func (h *AuthHandler) GetAPIKeyUsage(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid API key ID",
+		})
+		return
+	}
+
+	usage, err := h.authService.GetAPIKeyUsage(c.Request.Context(), userID.(uint64), keyID, 7*24*time.Hour)
+	if err != nil {
+		if err == repository.ErrAPIKeyNotFound || err == services.ErrAPIKeyNotOwned {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "not_found",
+				Message: "API key not found",
+			})
+			return
+		}
+		h.logger.Error("Failed to get API key usage", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to get API key usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetMyAuditLog returns the current user's own audit trail, optionally
+// narrowed by event_type, from, and to query parameters.
+// @Summary Get my audit log
+// @Description List audit events for the currently authenticated user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param event_type query string false "Filter by event type"
+// @Param from query string false "RFC3339 start of time range"
+// @Param to query string false "RFC3339 end of time range"
+// @Param limit query int false "Max results (default 50, max 200)"
+// @Param offset query int false "Pagination offset"
+// @Success 200 {array} models.AuditEventResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/me/audit [get]
+func (h *AuthHandler) GetMyAuditLog(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid audit filter",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	events, err := h.auditLogger.ListForUser(c.Request.Context(), userID.(uint64), filter)
+	if err != nil {
+		h.logger.Error("Failed to list audit events", "error", err, "user_id", userID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to list audit events",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetAuditLog returns the audit trail across all users, optionally
+// narrowed by user_id, event_type, from, and to query parameters.
+// @Summary Get audit log (admin)
+// @Description List audit events across all users
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "Filter by user ID"
+// @Param event_type query string false "Filter by event type"
+// @Param from query string false "RFC3339 start of time range"
+// @Param to query string false "RFC3339 end of time range"
+// @Param limit query int false "Max results (default 50, max 200)"
+// @Param offset query int false "Pagination offset"
+// @Success 200 {array} models.AuditEventResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /admin/audit [get]
+func (h *AuthHandler) GetAuditLog(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid audit filter",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_id",
+				Message: "Invalid user ID",
+			})
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	events, err := h.auditLogger.ListAll(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list audit events", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to list audit events",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// parseAuditFilter builds a models.AuditFilter from a request's query
+// parameters, shared by GetMyAuditLog and GetAuditLog.
+func parseAuditFilter(c *gin.Context) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		EventType: c.Query("event_type"),
+		Limit:     50,
+	}
+
+	if from := c.Query("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, err
+		}
+		filter.From = parsed
+	}
+
+	if to := c.Query("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, err
+		}
+		filter.To = parsed
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.Limit = limit
+	}
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 200
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// ListUsers searches and paginates users for admin consumption.
+// @Summary List/search users (admin)
+// @Description Search users with filters and pagination
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param email query string false "Exact email match"
+// @Param q query string false "Email substring match"
+// @Param role query string false "Filter by role"
+// @Param is_active query bool false "Filter by active status"
+// @Param created_after query string false "RFC3339 lower bound on created_at"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {array} models.UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /admin/users [get]
+func (h *AuthHandler) ListUsers(c *gin.Context) {
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid filter",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	users, total, err := h.authService.SearchUsers(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to search users", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to list users",
+		})
+		return
+	}
+
+	responses := make([]*models.UserResponse, len(users))
+	for i, u := range users {
+		responses[i] = u.ToResponse()
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if link := buildPaginationLink(c, filter.Page, filter.PageSize, total); link != "" {
+		c.Header("Link", link)
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// parseUserFilter builds a models.UserFilter from GET /admin/users's query
+// parameters.
+func parseUserFilter(c *gin.Context) (models.UserFilter, error) {
+	filter := models.UserFilter{
+		Email:    c.Query("email"),
+		Role:     c.Query("role"),
+		Query:    c.Query("q"),
+		Page:     1,
+		PageSize: 20,
+	}
+
+	if isActiveStr := c.Query("is_active"); isActiveStr != "" {
+		isActive, err := strconv.ParseBool(isActiveStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.IsActive = &isActive
+	}
+
+	if createdAfter := c.Query("created_after"); createdAfter != "" {
+		parsed, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			return filter, err
+		}
+		filter.CreatedAfter = parsed
+	}
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.Page = page
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	if pageSizeStr := c.Query("page_size"); pageSizeStr != "" {
+		pageSize, err := strconv.Atoi(pageSizeStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.PageSize = pageSize
+	}
+	if filter.PageSize <= 0 || filter.PageSize > 100 {
+		filter.PageSize = 100
+	}
+
+	return filter, nil
+}
+
+// buildPaginationLink builds an RFC 5988 Link header with prev/next page
+// URLs, or "" once there's no next page and no previous page to point to.
+func buildPaginationLink(c *gin.Context, page, pageSize int, total int64) string {
+	lastPage := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	pageURL := func(p int) string {
+		q := c.Request.URL.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("page_size", strconv.Itoa(pageSize))
+		u := *c.Request.URL
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	var links []string
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(page-1)))
+	}
+	if page < lastPage {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)))
+	}
+	return strings.Join(links, ", ")
+}
+
+// UpdateUserAdmin changes a user's role and/or active status.
+// @Summary Update a user (admin)
+// @Description Change a user's role and/or activate/deactivate them
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body models.AdminUpdateUserRequest true "Fields to update"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{id} [patch]
+func (h *AuthHandler) UpdateUserAdmin(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid_id", Message: "Invalid user ID"})
+		return
+	}
+
+	var req models.AdminUpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "validation_error", Message: "Invalid request body", Details: err.Error()})
+		return
+	}
+
+	adminUserID, _ := c.Get("user_id")
+	user, err := h.authService.AdminUpdateUser(c.Request.Context(), targetID, &req, adminUserID.(uint64), middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "not_found", Message: "User not found"})
+			return
+		}
+		h.logger.Error("Failed to update user", "error", err, "target_user_id", targetID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal_error", Message: "Failed to update user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+// DeleteUserAdmin soft-deletes a user.
+// @Summary Delete a user (admin)
+// @Description Soft-deletes a user by deactivating their account
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{id} [delete]
+func (h *AuthHandler) DeleteUserAdmin(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid_id", Message: "Invalid user ID"})
+		return
+	}
+
+	adminUserID, _ := c.Get("user_id")
+	if err := h.authService.AdminDeleteUser(c.Request.Context(), targetID, adminUserID.(uint64), middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id")); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "not_found", Message: "User not found"})
+			return
+		}
+		h.logger.Error("Failed to delete user", "error", err, "target_user_id", targetID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal_error", Message: "Failed to delete user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Message: "User deleted"})
+}
+
+// ResetUserPassword generates a one-time password reset token for a user.
+// @Summary Reset a user's password (admin)
+// @Description Generates a one-time password reset token for an admin to relay to the user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.AdminResetPasswordResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{id}/reset-password [post]
+func (h *AuthHandler) ResetUserPassword(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid_id", Message: "Invalid user ID"})
+		return
+	}
+
+	adminUserID, _ := c.Get("user_id")
+	resp, err := h.authService.AdminResetPassword(c.Request.Context(), targetID, adminUserID.(uint64), middleware.ClientIP(c), c.Request.UserAgent(), c.GetString("request_id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "not_found", Message: "User not found"})
+			return
+		}
+		h.logger.Error("Failed to reset user password", "error", err, "target_user_id", targetID)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal_error", Message: "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+// SuccessResponse represents a success response
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
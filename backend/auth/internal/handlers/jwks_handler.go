@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/captcha-platform/auth/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler serves the service's public signing keys as a JSON Web Key Set
+type JWKSHandler struct {
+	keyManager *jwt.KeyManager
+}
+
+// NewJWKSHandler creates a new JWKSHandler. keyManager may be nil when the
+// service is configured for HS256, in which case an empty key set is served.
+func NewJWKSHandler(keyManager *jwt.KeyManager) *JWKSHandler {
+	return &JWKSHandler{keyManager: keyManager}
+}
+
+// JWKS serves the current and still-valid retired public signing keys
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying RS256-signed access tokens
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	if h.keyManager == nil {
+		c.JSON(http.StatusOK, gin.H{"keys": []jwt.JWK{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": h.keyManager.JWKS()})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/captcha-platform/auth/internal/config"
+	"github.com/captcha-platform/auth/internal/services"
+	"github.com/captcha-platform/auth/internal/services/social"
+	"github.com/captcha-platform/auth/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const oauthStateCookie = "oauth_state"
+
+// OAuthHandler handles the OIDC/social login authorization-code flow
+type OAuthHandler struct {
+	authService *services.AuthService
+	oidcConfig  config.OIDCConfig
+	providers   *social.Registry
+	logger      *logger.Logger
+}
+
+// NewOAuthHandler creates a new OAuthHandler
+func NewOAuthHandler(authService *services.AuthService, oidcConfig config.OIDCConfig, providers *social.Registry, logger *logger.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		authService: authService,
+		oidcConfig:  oidcConfig,
+		providers:   providers,
+		logger:      logger,
+	}
+}
+
+// authMethod describes one configured login method, for GET /auth/methods.
+type authMethod struct {
+	Provider string `json:"provider"`
+	LoginURL string `json:"login_url"`
+}
+
+// Methods reports which login methods are available, so a client can render
+// the right set of buttons without hardcoding provider names.
+// @Summary List available login methods
+// @Description Returns every enabled identity provider and its login URL, alongside password login
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} gin.H
+// @Router /auth/methods [get]
+func (h *OAuthHandler) Methods(c *gin.Context) {
+	methods := make([]authMethod, 0, len(h.oidcConfig.Providers))
+	for name := range h.oidcConfig.Providers {
+		methods = append(methods, authMethod{
+			Provider: name,
+			LoginURL: "/auth/oauth/" + name + "/authorize",
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"password": true,
+		"oauth":    methods,
+	})
+}
+
+// Authorize redirects the user to the given provider's authorization endpoint
+// @Summary Start OIDC/social login
+// @Description Redirect to the provider's authorization endpoint
+// @Tags oauth
+// @Param provider path string true "Login type (e.g. google, github)"
+// @Router /auth/oauth/{provider}/authorize [get]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	_, provider, ok := h.resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	state, err := randomState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal_error", Message: "Failed to start login"})
+		return
+	}
+	c.SetCookie(oauthStateCookie, state, 600, "/", "", false, true)
+
+	c.Redirect(http.StatusFound, provider.Authorize(state))
+}
+
+// Callback handles the provider's redirect back with an authorization code
+// @Summary OIDC/social login callback
+// @Description Exchange the authorization code and find-or-create the local user
+// @Tags oauth
+// @Param provider path string true "Login type (e.g. google, github)"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /auth/oauth/{provider}/callback [get]
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	providerName, provider, ok := h.resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	state := c.Query("state")
+	cookieState, err := c.Cookie(oauthStateCookie)
+	if err != nil || state == "" || state != cookieState {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_state",
+			Message: "Missing or mismatched OAuth state",
+		})
+		return
+	}
+	c.SetCookie(oauthStateCookie, "", -1, "/", "", false, true)
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "missing_code",
+			Message: "Authorization code is required",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	info, err := provider.Exchange(ctx, code)
+	if err != nil {
+		h.logger.Error("Failed to exchange OAuth code", "provider", providerName, "error", err)
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: "provider_unavailable", Message: "Failed to complete login with identity provider"})
+		return
+	}
+
+	var expiry *time.Time
+	if info.ExpiresIn > 0 {
+		t := time.Now().Add(time.Duration(info.ExpiresIn) * time.Second)
+		expiry = &t
+	}
+
+	// If the caller is already authenticated, this is a "link provider to my account" request.
+	var currentUserID *uint64
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(uint64); ok {
+			currentUserID = &id
+		}
+	}
+
+	response, err := h.authService.CompleteOAuthCallback(ctx, providerName, info.Subject, info.Email, info.AccessToken, info.RefreshToken, expiry, currentUserID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if err == services.ErrLoginMethodMismatch {
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Error:   "login_method_mismatch",
+				Message: "This email is already registered with a password. Log in and link this provider explicitly.",
+			})
+			return
+		}
+		if err == services.ErrOAuthEmailMissing {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "oauth_email_missing",
+				Message: "This provider didn't share a verified email. Log in to an existing account first to link it.",
+			})
+			return
+		}
+		h.logger.Error("Failed to complete OAuth callback", "provider", providerName, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal_error", Message: "Failed to complete login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// resolveProvider validates the :provider path param against the registry of
+// providers built at startup.
+func (h *OAuthHandler) resolveProvider(c *gin.Context) (string, social.IdentityProvider, bool) {
+	name := c.Param("provider")
+	provider, exists := h.providers.Get(name)
+	if !exists {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "unknown_provider",
+			Message: "Unknown or unconfigured identity provider",
+		})
+		return "", nil, false
+	}
+	return name, provider, true
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
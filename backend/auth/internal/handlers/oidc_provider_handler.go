@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/captcha-platform/auth/internal/config"
+	"github.com/captcha-platform/auth/internal/models"
+	"github.com/captcha-platform/auth/internal/repository"
+	"github.com/captcha-platform/auth/internal/services"
+	"github.com/captcha-platform/auth/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCProviderHandler serves this service's own OIDC/OAuth2 provider (OP)
+// endpoints, letting other services (e.g. captcha-service consumers)
+// federate sign-in through it. This is separate from OAuthHandler, which
+// is the relying-party side that consumes *external* OIDC providers for
+// social login.
+type OIDCProviderHandler struct {
+	service  *services.OIDCProviderService
+	opConfig config.OPConfig
+	logger   *logger.Logger
+}
+
+// NewOIDCProviderHandler creates a new OIDCProviderHandler.
+func NewOIDCProviderHandler(service *services.OIDCProviderService, opConfig config.OPConfig, logger *logger.Logger) *OIDCProviderHandler {
+	return &OIDCProviderHandler{service: service, opConfig: opConfig, logger: logger}
+}
+
+// Discovery serves the OIDC discovery document.
+// @Summary OIDC discovery document
+// @Description Metadata describing this service's OIDC provider endpoints and capabilities
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} models.OIDCDiscoveryDocument
+// @Router /.well-known/openid-configuration [get]
+func (h *OIDCProviderHandler) Discovery(c *gin.Context) {
+	issuer := strings.TrimSuffix(h.opConfig.Issuer, "/")
+
+	c.JSON(http.StatusOK, models.OIDCDiscoveryDocument{
+		Issuer:                           issuer,
+		AuthorizationEndpoint:            issuer + "/oidc/authorize",
+		TokenEndpoint:                    issuer + "/oidc/token",
+		UserinfoEndpoint:                 issuer + "/oidc/userinfo",
+		JWKSURI:                          issuer + "/oidc/jwks.json",
+		IntrospectionEndpoint:            issuer + "/oidc/introspect",
+		ResponseTypesSupported:           []string{"code"},
+		GrantTypesSupported:              []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{"RS256"},
+		CodeChallengeMethodsSupported:    []string{"S256"},
+		ScopesSupported:                  []string{"openid", "email", "profile"},
+	})
+}
+
+// Authorize validates the authorization request and redirects back to the
+// relying party with a single-use authorization code. The caller must
+// already be authenticated (see AuthRequired) - this service has no
+// interactive login page of its own, so there's no session to start here.
+// @Summary Authorization endpoint
+// @Description PKCE-required authorization code flow; redirects to redirect_uri with a code
+// @Tags oidc
+// @Security BearerAuth
+// @Param client_id query string true "Relying party's client ID"
+// @Param redirect_uri query string true "Must match one of the client's registered redirect URIs"
+// @Param response_type query string true "Must be 'code'"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be 'S256'"
+// @Param state query string false "Opaque value echoed back to the client"
+// @Param nonce query string false "Echoed back in the ID token"
+// @Router /oidc/authorize [get]
+func (h *OIDCProviderHandler) Authorize(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "User not authenticated"})
+		return
+	}
+
+	if c.Query("response_type") != "code" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_response_type"})
+		return
+	}
+
+	redirectURI := c.Query("redirect_uri")
+
+	code, err := h.service.Authorize(
+		c.Request.Context(),
+		c.Query("client_id"),
+		redirectURI,
+		c.Query("code_challenge"),
+		c.Query("code_challenge_method"),
+		c.Query("nonce"),
+		userIDVal.(uint64),
+	)
+	if err != nil {
+		status, body := oidcErrorResponse(err)
+		c.JSON(status, body)
+		return
+	}
+
+	redirectURL := redirectURI + "?code=" + url.QueryEscape(code)
+	if state := c.Query("state"); state != "" {
+		redirectURL += "&state=" + url.QueryEscape(state)
+	}
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// Token exchanges an authorization code, refresh token, or the client's own
+// credentials for tokens, per the grant_type form field.
+// @Summary Token endpoint
+// @Description Exchanges an authorization_code, refresh_token, or client_credentials grant for tokens
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} models.OIDCTokenResponse
+// @Router /oidc/token [post]
+func (h *OIDCProviderHandler) Token(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+
+	clientID := c.Request.PostFormValue("client_id")
+	clientSecret := c.Request.PostFormValue("client_secret")
+
+	var resp *models.OIDCTokenResponse
+	var err error
+
+	switch c.Request.PostFormValue("grant_type") {
+	case "authorization_code":
+		resp, err = h.service.Token(
+			c.Request.Context(),
+			clientID,
+			clientSecret,
+			c.Request.PostFormValue("redirect_uri"),
+			c.Request.PostFormValue("code"),
+			c.Request.PostFormValue("code_verifier"),
+		)
+	case "refresh_token":
+		resp, err = h.service.RefreshToken(
+			c.Request.Context(),
+			clientID,
+			clientSecret,
+			c.Request.PostFormValue("refresh_token"),
+		)
+	case "client_credentials":
+		resp, err = h.service.ClientCredentials(c.Request.Context(), clientID, clientSecret)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	if err != nil {
+		status, body := oidcErrorResponse(err)
+		c.JSON(status, body)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UserInfo returns the OIDC userinfo claims for the bearer access token's subject.
+// @Summary Userinfo endpoint
+// @Tags oidc
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.OIDCUserInfoResponse
+// @Router /oidc/userinfo [get]
+func (h *OIDCProviderHandler) UserInfo(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "User not authenticated"})
+		return
+	}
+
+	resp, err := h.service.UserInfo(c.Request.Context(), userIDVal.(uint64))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "not_found", Message: "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Introspect reports whether a token is currently active, per RFC 7662.
+// Intended for trusted relying parties only.
+// @Summary Token introspection endpoint
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} models.OIDCIntrospectResponse
+// @Router /oidc/introspect [post]
+func (h *OIDCProviderHandler) Introspect(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.service.Introspect(c.Request.PostFormValue("token")))
+}
+
+// RegisterClient registers a new OAuth2/OIDC relying party. Admin-only - see
+// AdminRequired in the route setup.
+// @Summary Register an OIDC client
+// @Tags oidc
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 201 {object} registerClientResponse
+// @Router /oidc/clients [post]
+func (h *OIDCProviderHandler) RegisterClient(c *gin.Context) {
+	var req registerClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid_request", Message: err.Error()})
+		return
+	}
+
+	client, secret, err := h.service.RegisterClient(c.Request.Context(), req.Name, req.RedirectURIs, req.AllowedGrantTypes, req.AllowedScopes, req.Confidential)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "server_error", Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, registerClientResponse{
+		ClientID:     client.ClientID,
+		ClientSecret: secret,
+		Name:         client.Name,
+	})
+}
+
+type registerClientRequest struct {
+	Name              string   `json:"name" binding:"required"`
+	RedirectURIs      []string `json:"redirect_uris" binding:"required"`
+	AllowedGrantTypes []string `json:"allowed_grant_types" binding:"required"`
+	AllowedScopes     []string `json:"allowed_scopes" binding:"required"`
+	Confidential      bool     `json:"confidential"`
+}
+
+// registerClientResponse echoes the new client's credentials. ClientSecret
+// is only ever returned here, in plaintext, at registration time - only its
+// bcrypt hash is persisted.
+type registerClientResponse struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Name         string `json:"name"`
+}
+
+func oidcErrorResponse(err error) (int, gin.H) {
+	switch err {
+	case repository.ErrOIDCClientNotFound:
+		return http.StatusBadRequest, gin.H{"error": "invalid_client"}
+	case services.ErrInvalidRedirectURI:
+		return http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()}
+	case services.ErrPKCERequired, services.ErrUnsupportedPKCEMethod, services.ErrInvalidCodeVerifier:
+		return http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()}
+	case repository.ErrAuthorizationCodeNotFound, repository.ErrOIDCRefreshTokenNotFound:
+		return http.StatusBadRequest, gin.H{"error": "invalid_grant"}
+	case services.ErrInvalidRefreshToken:
+		return http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": err.Error()}
+	case services.ErrUnauthorizedClient:
+		return http.StatusBadRequest, gin.H{"error": "unauthorized_client", "error_description": err.Error()}
+	case services.ErrInvalidClientSecret:
+		return http.StatusUnauthorized, gin.H{"error": "invalid_client", "error_description": err.Error()}
+	case services.ErrOIDCNotConfigured:
+		return http.StatusNotImplemented, gin.H{"error": "unsupported", "error_description": err.Error()}
+	default:
+		return http.StatusInternalServerError, gin.H{"error": "server_error"}
+	}
+}
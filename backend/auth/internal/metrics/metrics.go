@@ -0,0 +1,130 @@
+// Package metrics exports the auth service's Prometheus metrics: request
+// counters and latency histograms labeled by route/status, login/MFA
+// rate-limit rejections, JWT verification failures, DB connection pool
+// stats, and Redis command latency. Everything here is registered against
+// the default registry and served at /metrics via promhttp.Handler in
+// cmd/main.go.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPRequestsTotal counts every request the service has handled,
+	// labeled by method, the matched route template (not the raw path, so
+	// "/api/v1/api-keys/:id" doesn't fragment into one series per ID), and
+	// response status.
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_http_requests_total",
+		Help: "Total number of HTTP requests handled by the auth service.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes request latency, same labels as
+	// HTTPRequestsTotal.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auth_http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RateLimitRejections counts requests a rate-limit middleware blocked:
+	// LoginRateLimiter ("login"), MFAVerifyRateLimiter ("mfa_verify"), and
+	// PerIPRequestLimiter/PerEmailRequestLimiter, labeled by the name they
+	// were given in cmd/main.go.
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_rate_limit_rejections_total",
+		Help: "Total number of requests rejected by a rate-limit middleware.",
+	}, []string{"limiter"})
+
+	// JWTVerificationFailures counts tokens AuthRequired rejected, labeled by
+	// the reason it gave up for. OptionalAuth does not record here.
+	JWTVerificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_jwt_verification_failures_total",
+		Help: "Total number of JWT verification failures, labeled by reason.",
+	}, []string{"reason"})
+
+	// DBPoolOpenConnections/DBPoolInUse/DBPoolIdle mirror sql.DBStats, kept
+	// current by StartDBPoolCollector.
+	DBPoolOpenConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "auth_db_pool_open_connections",
+		Help: "Number of established database connections (in-use plus idle).",
+	})
+	DBPoolInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "auth_db_pool_in_use_connections",
+		Help: "Number of database connections currently in use.",
+	})
+	DBPoolIdle = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "auth_db_pool_idle_connections",
+		Help: "Number of idle database connections in the pool.",
+	})
+	DBPoolWaitCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_db_pool_wait_count_total",
+		Help: "Total number of connections waited for because the pool was at MaxOpenConns.",
+	})
+
+	// RedisCommandDuration observes go-redis command latency, labeled by
+	// command name - see redisMetricsHook in redis_hook.go.
+	RedisCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auth_redis_command_duration_seconds",
+		Help:    "Redis command latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"command"})
+)
+
+// Middleware records HTTPRequestsTotal/HTTPRequestDuration for every
+// request. It must run after gin's route matching (i.e. anywhere in the
+// global chain), since it reads c.FullPath() once the handler has returned.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		HTTPRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
+		HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// StartDBPoolCollector polls db.Stats() every interval until ctx is done,
+// keeping the DBPool* gauges current. sql.DB has no native Prometheus
+// hook, so polling is the standard way to expose its pool stats.
+func StartDBPoolCollector(ctx context.Context, db *sql.DB, interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastWait := int64(0)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				stats := db.Stats()
+				DBPoolOpenConnections.Set(float64(stats.OpenConnections))
+				DBPoolInUse.Set(float64(stats.InUse))
+				DBPoolIdle.Set(float64(stats.Idle))
+				if delta := stats.WaitCount - lastWait; delta > 0 {
+					DBPoolWaitCount.Add(float64(delta))
+				}
+				lastWait = stats.WaitCount
+			}
+		}
+	}()
+}
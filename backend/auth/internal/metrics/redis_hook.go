@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisMetricsHook observes RedisCommandDuration for every command run
+// through a client it's attached to via Client.AddHook.
+type redisMetricsHook struct{}
+
+// NewRedisMetricsHook creates a redis.Hook that records RedisCommandDuration
+// per command name.
+func NewRedisMetricsHook() redis.Hook {
+	return redisMetricsHook{}
+}
+
+func (redisMetricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisMetricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		RedisCommandDuration.WithLabelValues(cmd.Name()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (redisMetricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start).Seconds()
+		for _, cmd := range cmds {
+			RedisCommandDuration.WithLabelValues(cmd.Name()).Observe(elapsed)
+		}
+		return err
+	}
+}
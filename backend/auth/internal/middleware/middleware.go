@@ -1,16 +1,27 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/captcha-platform/auth/internal/config"
+	"github.com/captcha-platform/auth/internal/metrics"
 	"github.com/captcha-platform/auth/pkg/jwt"
 	"github.com/captcha-platform/auth/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger returns a middleware that logs requests
@@ -39,12 +50,21 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 	}
 }
 
-// RequestID adds a unique request ID to each request
+// RequestID adds a unique request ID to each request. When otelgin has
+// already started a span for this request (it must run ahead of this
+// middleware in the chain), its trace ID is adopted as the request ID
+// instead of a fresh UUID, so a log line and its trace are the same
+// identifier - no separate correlation step needed to go from one to the
+// other.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
 		if requestID == "" {
-			requestID = uuid.New().String()
+			if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.HasTraceID() {
+				requestID = spanCtx.TraceID().String()
+			} else {
+				requestID = uuid.New().String()
+			}
 		}
 
 		c.Set("request_id", requestID)
@@ -54,6 +74,20 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
+// ClientIP returns the caller's IP, preferring the first hop of the
+// X-Forwarded-For header (set by the gateway, which sits directly in
+// front of this service) over gin's own RemoteAddr-based resolution. Used
+// wherever an IP is recorded for forensic purposes, e.g. audit events and
+// the login lockout tracker.
+func ClientIP(c *gin.Context) string {
+	if xff := c.GetHeader("X-Forwarded-For"); xff != "" {
+		if ip := strings.TrimSpace(strings.Split(xff, ",")[0]); ip != "" {
+			return ip
+		}
+	}
+	return c.ClientIP()
+}
+
 // CORS returns a middleware that handles CORS
 func CORS(cfg config.CORSConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -87,15 +121,298 @@ func CORS(cfg config.CORSConfig) gin.HandlerFunc {
 	}
 }
 
+// NewRedisClient creates a new Redis client, used for login rate limiting
+// and token idle-timeout tracking
+func NewRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	client.AddHook(metrics.NewRedisMetricsHook())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// validateAccessToken picks the verification path by the token's own "alg"
+// header rather than trusting jwtCfg.SigningMethod alone, so this service
+// accepts both legacy HS256 access tokens and RS256 tokens (including OIDC
+// ID tokens presented as bearer tokens) at the same time - a deployment
+// migrating to RS256 still has unexpired HS256 tokens in flight, and an RP
+// using this service's OP mode only ever holds RS256-signed tokens.
+func validateAccessToken(ctx context.Context, token string, jwtCfg config.JWTConfig, keyManager *jwt.KeyManager, validator *jwt.Validator) (*jwt.AccessClaims, error) {
+	useRS256 := keyManager != nil && isRS256Token(token)
+
+	if validator != nil {
+		if useRS256 {
+			return validator.ValidateAccessTokenRS(ctx, token, keyManager)
+		}
+		return validator.ValidateAccessToken(ctx, token, jwtCfg.Secret)
+	}
+
+	if useRS256 {
+		return jwt.ValidateAccessTokenRS(token, keyManager)
+	}
+	return jwt.ValidateAccessToken(token, jwtCfg.Secret)
+}
+
+// isRS256Token reports whether tokenString's header declares the RS256
+// algorithm, without verifying its signature - callers still must do that
+// via the matching Validate* function before trusting any claims.
+func isRS256Token(tokenString string) bool {
+	headerSegment, _, ok := strings.Cut(tokenString, ".")
+	if !ok {
+		return false
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(headerSegment)
+	if err != nil {
+		return false
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return false
+	}
+
+	return header.Alg == "RS256"
+}
+
+// LoginRateLimiter limits failed login attempts using a Redis-backed sliding
+// window keyed on (email, IP). Only attempts that actually fail
+// authentication count against the budget; successful logins are free.
+func LoginRateLimiter(client *redis.Client, cfg config.AuthRateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if client == nil || cfg.MaxAttempts <= 0 {
+			c.Next()
+			return
+		}
+
+		var body struct {
+			Email string `json:"email"`
+		}
+		_ = c.ShouldBindBodyWith(&body, binding.JSON)
+
+		ctx := c.Request.Context()
+		key := fmt.Sprintf("login_attempts:%s:%s", body.Email, c.ClientIP())
+		now := time.Now()
+		windowStart := now.Add(-cfg.Window)
+
+		client.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(windowStart.UnixNano(), 10))
+
+		count, err := client.ZCard(ctx, key).Result()
+		if err == nil && count >= int64(cfg.MaxAttempts) {
+			metrics.RateLimitRejections.WithLabelValues("login").Inc()
+			c.Header("Retry-After", strconv.Itoa(int(cfg.Window.Seconds())))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "rate_limit_exceeded",
+				"message": "Too many failed login attempts, please try again later",
+			})
+			return
+		}
+
+		c.Next()
+
+		if c.Writer.Status() == http.StatusUnauthorized {
+			member := strconv.FormatInt(now.UnixNano(), 10)
+			client.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+			client.Expire(ctx, key, cfg.Window)
+		}
+	}
+}
+
+// MFAVerifyRateLimiter limits attempts to complete POST /auth/login/mfa,
+// using the same Redis-backed sliding window as LoginRateLimiter but keyed
+// on the pending token's hash rather than (email, IP) since the caller
+// isn't resolved to a user until the token validates.
+func MFAVerifyRateLimiter(client *redis.Client, cfg config.AuthRateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if client == nil || cfg.MaxAttempts <= 0 {
+			c.Next()
+			return
+		}
+
+		var body struct {
+			PendingToken string `json:"mfa_pending_token"`
+		}
+		_ = c.ShouldBindBodyWith(&body, binding.JSON)
+		if body.PendingToken == "" {
+			c.Next()
+			return
+		}
+
+		sum := sha256.Sum256([]byte(body.PendingToken))
+		ctx := c.Request.Context()
+		key := fmt.Sprintf("mfa_verify_attempts:%s", hex.EncodeToString(sum[:]))
+		now := time.Now()
+		windowStart := now.Add(-cfg.Window)
+
+		client.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(windowStart.UnixNano(), 10))
+
+		count, err := client.ZCard(ctx, key).Result()
+		if err == nil && count >= int64(cfg.MaxAttempts) {
+			metrics.RateLimitRejections.WithLabelValues("mfa_verify").Inc()
+			c.Header("Retry-After", strconv.Itoa(int(cfg.Window.Seconds())))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "rate_limit_exceeded",
+				"message": "Too many MFA attempts, please try again later",
+			})
+			return
+		}
+
+		c.Next()
+
+		if c.Writer.Status() == http.StatusUnauthorized {
+			member := strconv.FormatInt(now.UnixNano(), 10)
+			client.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+			client.Expire(ctx, key, cfg.Window)
+		}
+	}
+}
+
+// PerIPRequestLimiter caps request volume per client IP using a Redis-backed
+// sliding window, counting every request regardless of outcome. It's a
+// blunt pre-filter meant to sit in front of endpoints like /auth/register
+// that have no other throttling, and is independent of LoginRateLimiter's
+// failure-only count - a caller can trip this one even with all-successful
+// requests.
+func PerIPRequestLimiter(client *redis.Client, name string, cfg config.AuthRateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if client == nil || cfg.MaxAttempts <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := fmt.Sprintf("ip_requests:%s:%s", name, c.ClientIP())
+		now := time.Now()
+		windowStart := now.Add(-cfg.Window)
+
+		client.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(windowStart.UnixNano(), 10))
+
+		count, err := client.ZCard(ctx, key).Result()
+		if err == nil && count >= int64(cfg.MaxAttempts) {
+			metrics.RateLimitRejections.WithLabelValues(name).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(cfg.Window.Seconds())))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "rate_limit_exceeded",
+				"message": "Too many requests, please try again later",
+			})
+			return
+		}
+
+		member := strconv.FormatInt(now.UnixNano(), 10)
+		client.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+		client.Expire(ctx, key, cfg.Window)
+
+		c.Next()
+	}
+}
+
+// PerEmailRequestLimiter caps request volume per target email address using
+// a Redis-backed sliding window, counting every request regardless of
+// outcome. It complements PerIPRequestLimiter on endpoints like
+// /auth/forgot-password that take an email but have no failed-attempt
+// signal to key a LoginRateLimiter-style limiter on, so a single email can't
+// be mail-bombed from a rotating set of IPs.
+func PerEmailRequestLimiter(client *redis.Client, name string, cfg config.AuthRateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if client == nil || cfg.MaxAttempts <= 0 {
+			c.Next()
+			return
+		}
+
+		var body struct {
+			Email string `json:"email"`
+		}
+		_ = c.ShouldBindBodyWith(&body, binding.JSON)
+		if body.Email == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := fmt.Sprintf("email_requests:%s:%s", name, strings.ToLower(body.Email))
+		now := time.Now()
+		windowStart := now.Add(-cfg.Window)
+
+		client.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(windowStart.UnixNano(), 10))
+
+		count, err := client.ZCard(ctx, key).Result()
+		if err == nil && count >= int64(cfg.MaxAttempts) {
+			metrics.RateLimitRejections.WithLabelValues(name).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(cfg.Window.Seconds())))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "rate_limit_exceeded",
+				"message": "Too many requests for this account, please try again later",
+			})
+			return
+		}
+
+		member := strconv.FormatInt(now.UnixNano(), 10)
+		client.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+		client.Expire(ctx, key, cfg.Window)
+
+		c.Next()
+	}
+}
+
+// RequirePeerSPIFFE verifies the mTLS peer certificate's SPIFFE ID against
+// allowedIDs and, when it matches, marks the request as gateway-trusted so
+// AuthRequired will honor its X-User-* identity headers instead of
+// requiring its own JWT - without this, any caller that could reach the
+// auth service directly could set those headers itself and impersonate
+// anyone. It never aborts: a connection that isn't mTLS, or whose peer
+// isn't on the allow-list, just falls through to AuthRequired's normal JWT
+// path, same as before this existed. allowedIDs empty disables the check
+// entirely (the default - set ALLOWED_GATEWAY_SPIFFE_IDS to enable it).
+func RequirePeerSPIFFE(allowedIDs []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedIDs))
+	for _, id := range allowedIDs {
+		allowed[id] = true
+	}
+
+	return func(c *gin.Context) {
+		if len(allowed) == 0 || c.Request.TLS == nil {
+			c.Next()
+			return
+		}
+
+		for _, cert := range c.Request.TLS.PeerCertificates {
+			for _, uri := range cert.URIs {
+				if uri.Scheme == "spiffe" && allowed[uri.String()] {
+					c.Set("gateway_trusted", true)
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
 // AuthRequired returns a middleware that requires JWT authentication
-func AuthRequired(secret string) gin.HandlerFunc {
+func AuthRequired(jwtCfg config.JWTConfig, keyManager *jwt.KeyManager, validator *jwt.Validator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// First check for X-User-ID header (forwarded from gateway)
+		// First check for X-User-ID header (forwarded from gateway), but
+		// only honor it once RequirePeerSPIFFE has verified this connection
+		// actually is the gateway - otherwise these headers are trivially
+		// spoofable by anything that can reach this service.
 		userIDHeader := c.GetHeader("X-User-ID")
 		userEmailHeader := c.GetHeader("X-User-Email")
 		userRoleHeader := c.GetHeader("X-User-Role")
 
-		if userIDHeader != "" && userEmailHeader != "" {
+		if c.GetBool("gateway_trusted") && userIDHeader != "" && userEmailHeader != "" {
 			// Request already authenticated by gateway
 			var userID uint64
 			if _, err := parseUint64(userIDHeader, &userID); err == nil {
@@ -110,6 +427,7 @@ func AuthRequired(secret string) gin.HandlerFunc {
 		// Fallback to JWT validation
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
+			metrics.JWTVerificationFailures.WithLabelValues("missing_header").Inc()
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
 				"message": "Authorization header is required",
@@ -120,6 +438,7 @@ func AuthRequired(secret string) gin.HandlerFunc {
 		// Check Bearer prefix
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			metrics.JWTVerificationFailures.WithLabelValues("malformed_header").Inc()
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
 				"message": "Invalid authorization header format",
@@ -130,8 +449,9 @@ func AuthRequired(secret string) gin.HandlerFunc {
 		token := parts[1]
 
 		// Validate token
-		claims, err := jwt.ValidateAccessToken(token, secret)
+		claims, err := validateAccessToken(c.Request.Context(), token, jwtCfg, keyManager, validator)
 		if err != nil {
+			metrics.JWTVerificationFailures.WithLabelValues("invalid_token").Inc()
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
 				"message": "Invalid or expired token",
@@ -148,6 +468,25 @@ func AuthRequired(secret string) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuth populates user context from a valid JWT if one is present,
+// but does not abort the request when it's missing or invalid. Used by
+// routes that behave differently for logged-in vs anonymous callers (e.g.
+// the OAuth callback, which links to the current user if there is one).
+func OptionalAuth(jwtCfg config.JWTConfig, keyManager *jwt.KeyManager, validator *jwt.Validator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			if claims, err := validateAccessToken(c.Request.Context(), parts[1], jwtCfg, keyManager, validator); err == nil {
+				c.Set("user_id", claims.UserID)
+				c.Set("user_email", claims.Email)
+				c.Set("user_role", claims.Role)
+			}
+		}
+		c.Next()
+	}
+}
+
 // AdminRequired returns a middleware that requires admin role
 func AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
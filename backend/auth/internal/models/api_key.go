@@ -2,24 +2,27 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
 // APIKey represents an API key in the system
 type APIKey struct {
-	ID            uint64         `json:"id"`
-	UserID        uint64         `json:"user_id"`
-	Name          string         `json:"name"`
-	KeyPrefix     string         `json:"key_prefix"`
-	KeyHash       string         `json:"-"` // Never expose key hash
-	Scopes        sql.NullString `json:"scopes,omitempty"`
-	RateLimit     int            `json:"rate_limit"`
-	TotalRequests uint64         `json:"total_requests"`
-	LastUsedAt    sql.NullTime   `json:"last_used_at,omitempty"`
-	IsActive      bool           `json:"is_active"`
-	ExpiresAt     sql.NullTime   `json:"expires_at,omitempty"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
+	ID              uint64         `json:"id"`
+	UserID          uint64         `json:"user_id"`
+	Name            string         `json:"name"`
+	KeyPrefix       string         `json:"key_prefix"`
+	KeyHash         string         `json:"-"` // Never expose key hash
+	OldKeyHash      sql.NullString `json:"-"` // Previous secret's hash, valid until OldKeyExpiresAt (set by rotation)
+	OldKeyExpiresAt sql.NullTime   `json:"-"`
+	Scopes          sql.NullString `json:"scopes,omitempty"`
+	RateLimit       int            `json:"rate_limit"`
+	TotalRequests   uint64         `json:"total_requests"`
+	LastUsedAt      sql.NullTime   `json:"last_used_at,omitempty"`
+	IsActive        bool           `json:"is_active"`
+	ExpiresAt       sql.NullTime   `json:"expires_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
 }
 
 // APIKeyResponse is the public representation of an API key
@@ -50,6 +53,53 @@ type CreateAPIKeyRequest struct {
 	ExpiresIn int      `json:"expires_in,omitempty"` // Duration in days
 }
 
+// APIKeyIntrospectResponse is returned by the internal introspection
+// endpoint the gateway calls to resolve a raw API key to its scopes and
+// per-key rate limit before proxying a request upstream.
+type APIKeyIntrospectResponse struct {
+	Valid     bool     `json:"valid"`
+	UserID    uint64   `json:"user_id,omitempty"`
+	KeyID     uint64   `json:"key_id,omitempty"`
+	KeyPrefix string   `json:"key_prefix,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+	RateLimit int      `json:"rate_limit,omitempty"`
+}
+
+// APIKeyUsageIncrement is one batched usage report from the gateway: keyID
+// was used count times since the last flush.
+type APIKeyUsageIncrement struct {
+	KeyID uint64 `json:"key_id"`
+	Count int    `json:"count"`
+}
+
+// APIKeyUsageBucket is the request count for one hour-sized bucket of an
+// API key's usage history.
+type APIKeyUsageBucket struct {
+	Bucket       time.Time `json:"bucket"`
+	RequestCount int       `json:"request_count"`
+}
+
+// APIKeyUsageResponse is returned by GET /api-keys/:id/usage.
+type APIKeyUsageResponse struct {
+	KeyID   uint64              `json:"key_id"`
+	Buckets []APIKeyUsageBucket `json:"buckets"`
+}
+
+// ScopeList decodes the JSON-encoded Scopes column into a slice, returning
+// an empty slice if it's unset or malformed.
+func (a *APIKey) ScopeList() []string {
+	if !a.Scopes.Valid || a.Scopes.String == "" {
+		return []string{}
+	}
+
+	var scopes []string
+	if err := json.Unmarshal([]byte(a.Scopes.String), &scopes); err != nil {
+		return []string{}
+	}
+
+	return scopes
+}
+
 // ToResponse converts an APIKey to APIKeyResponse
 func (a *APIKey) ToResponse() *APIKeyResponse {
 	response := &APIKeyResponse{
@@ -72,11 +122,7 @@ func (a *APIKey) ToResponse() *APIKeyResponse {
 		response.ExpiresAt = &formatted
 	}
 
-	// Parse scopes from JSON string
-	if a.Scopes.Valid && a.Scopes.String != "" {
-		// Simple parsing - in production, use proper JSON unmarshal
-		response.Scopes = []string{}
-	}
+	response.Scopes = a.ScopeList()
 
 	return response
-}
\ No newline at end of file
+}
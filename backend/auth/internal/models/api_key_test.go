@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// TestAPIKeyScopeList covers the JSON round-trip chunk3-5 asked for tests
+// on: CreateAPIKey encodes req.Scopes with encoding/json before storage, and
+// ScopeList decodes it back here. These cases exercise values that would
+// have broken a hand-rolled string-concat encoding (quotes, backslashes,
+// embedded JSON/array syntax) to confirm the real encoder/decoder pair
+// handles them safely instead.
+func TestAPIKeyScopeList(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored sql.NullString
+		want   []string
+	}{
+		{
+			name:   "unset column",
+			stored: sql.NullString{Valid: false},
+			want:   []string{},
+		},
+		{
+			name:   "empty string",
+			stored: sql.NullString{Valid: true, String: ""},
+			want:   []string{},
+		},
+		{
+			name:   "normal scopes",
+			stored: sql.NullString{Valid: true, String: `["captcha:solve","stats:read"]`},
+			want:   []string{"captcha:solve", "stats:read"},
+		},
+		{
+			name:   "scope value containing a quote",
+			stored: sql.NullString{Valid: true, String: `["captcha:solve\", \"admin:*"]`},
+			want:   []string{`captcha:solve", "admin:*`},
+		},
+		{
+			name:   "scope value containing a backslash",
+			stored: sql.NullString{Valid: true, String: `["captcha:\\solve"]`},
+			want:   []string{`captcha:\solve`},
+		},
+		{
+			name:   "malformed JSON falls back to empty",
+			stored: sql.NullString{Valid: true, String: `[captcha:solve]`},
+			want:   []string{},
+		},
+		{
+			name:   "non-array JSON falls back to empty",
+			stored: sql.NullString{Valid: true, String: `{"scope":"admin:*"}`},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &APIKey{Scopes: tt.stored}
+			got := key.ScopeList()
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("ScopeList() = %#v, want %#v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ScopeList() = %#v, want %#v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Audit event types recorded by services.AuditLogger. These are the
+// vocabulary of the event_type column - new callers should reuse one of
+// these constants rather than inventing ad-hoc strings.
+const (
+	AuditEventLoginSuccess           = "login.success"
+	AuditEventLoginFailure           = "login.failure"
+	AuditEventPasswordChange         = "password.change"
+	AuditEventAPIKeyCreated          = "apikey.created"
+	AuditEventAPIKeyDeleted          = "apikey.deleted"
+	AuditEventTokenRefresh           = "token.refresh"
+	AuditEventMFAEnroll              = "mfa.enroll"
+	AuditEventAdminUserUpdate        = "admin.user.update"
+	AuditEventAdminUserDelete        = "admin.user.delete"
+	AuditEventAdminPasswordReset     = "admin.user.password_reset"
+	AuditEventEmailVerified          = "email.verified"
+	AuditEventPasswordResetRequested = "password.reset_requested"
+	AuditEventPasswordResetCompleted = "password.reset_completed"
+)
+
+// AuditEvent is a single forensic record of a security-relevant action:
+// who (actor/UserID), from where (IP/UserAgent), which request
+// (RequestID), and what (EventType/Metadata). UserID is nil when the actor
+// couldn't be resolved to an account, e.g. a failed login against an
+// unknown email.
+type AuditEvent struct {
+	ID        uint64         `json:"id"`
+	UserID    sql.NullInt64  `json:"-"`
+	EventType string         `json:"event_type"`
+	Actor     string         `json:"actor"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	RequestID string         `json:"request_id"`
+	Metadata  sql.NullString `json:"-"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// AuditEventResponse is the public representation of an AuditEvent.
+type AuditEventResponse struct {
+	ID        uint64  `json:"id"`
+	UserID    *uint64 `json:"user_id,omitempty"`
+	EventType string  `json:"event_type"`
+	Actor     string  `json:"actor"`
+	IP        string  `json:"ip"`
+	UserAgent string  `json:"user_agent"`
+	RequestID string  `json:"request_id"`
+	Metadata  string  `json:"metadata,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// ToResponse converts an AuditEvent to AuditEventResponse.
+func (e *AuditEvent) ToResponse() *AuditEventResponse {
+	resp := &AuditEventResponse{
+		ID:        e.ID,
+		EventType: e.EventType,
+		Actor:     e.Actor,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		RequestID: e.RequestID,
+		CreatedAt: e.CreatedAt.Format(time.RFC3339),
+	}
+
+	if e.UserID.Valid {
+		userID := uint64(e.UserID.Int64)
+		resp.UserID = &userID
+	}
+
+	if e.Metadata.Valid {
+		resp.Metadata = e.Metadata.String
+	}
+
+	return resp
+}
+
+// AuditFilter narrows a GET .../audit query by time range, event type, and
+// (admin only) a specific user. A zero value matches everything.
+type AuditFilter struct {
+	UserID    *uint64
+	EventType string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
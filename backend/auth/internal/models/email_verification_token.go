@@ -0,0 +1,18 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// EmailVerificationToken is a single-use, short-lived credential emailed to
+// a user so they can confirm they control their registered address. Like
+// PasswordResetToken, only the hash of the opaque secret is persisted.
+type EmailVerificationToken struct {
+	ID        string       `json:"id"`
+	UserID    uint64       `json:"user_id"`
+	TokenHash string       `json:"-"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
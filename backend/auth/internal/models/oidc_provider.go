@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OIDCClient is a relying party registered to use this service's own OIDC
+// provider (OP) endpoints, as opposed to OIDCProviderConfig (config.go),
+// which configures external providers this service logs users in through.
+type OIDCClient struct {
+	ID                uint64
+	ClientID          string
+	ClientSecretHash  string // empty for public clients that rely on PKCE alone
+	Name              string
+	RedirectURIs      []string
+	AllowedGrantTypes []string // e.g. "authorization_code", "refresh_token", "client_credentials"
+	AllowedScopes     []string
+	CreatedAt         time.Time
+}
+
+// OIDCRefreshToken is a single-use, rotating refresh token issued alongside
+// an access/ID token by the authorization_code grant, scoped to the client
+// that requested it. Mirrors RefreshToken (refresh_token.go), which serves
+// the same purpose for this service's own login sessions.
+type OIDCRefreshToken struct {
+	ID        string
+	ClientID  string
+	UserID    uint64
+	TokenHash string
+	ParentID  sql.NullString
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+}
+
+// OIDCAuthorizationCode is a single-use code issued by GET /oidc/authorize
+// and consumed by POST /oidc/token.
+type OIDCAuthorizationCode struct {
+	Code                string
+	ClientID            string
+	UserID              uint64
+	RedirectURI         string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	ExpiresAt           time.Time
+}
+
+// OIDCTokenResponse is returned by POST /oidc/token.
+type OIDCTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// OIDCUserInfoResponse is returned by GET /oidc/userinfo.
+type OIDCUserInfoResponse struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email"`
+}
+
+// OIDCIntrospectResponse is returned by POST /oidc/introspect, per RFC 7662.
+type OIDCIntrospectResponse struct {
+	Active   bool   `json:"active"`
+	Subject  string `json:"sub,omitempty"`
+	Email    string `json:"email,omitempty"`
+	ExpireAt int64  `json:"exp,omitempty"`
+}
+
+// OIDCDiscoveryDocument is served at /.well-known/openid-configuration.
+type OIDCDiscoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	UserinfoEndpoint                 string   `json:"userinfo_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IntrospectionEndpoint            string   `json:"introspection_endpoint"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	GrantTypesSupported              []string `json:"grant_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+	ScopesSupported                  []string `json:"scopes_supported"`
+}
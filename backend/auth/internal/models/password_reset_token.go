@@ -0,0 +1,18 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PasswordResetToken is a single-use, short-lived credential that lets its
+// holder set a new password without knowing the old one. Like RefreshToken,
+// only the hash of the opaque secret is persisted.
+type PasswordResetToken struct {
+	ID        string       `json:"id"`
+	UserID    uint64       `json:"user_id"`
+	TokenHash string       `json:"-"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
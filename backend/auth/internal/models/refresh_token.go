@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RefreshToken represents a persisted, revocable refresh token. The client
+// only ever holds the opaque secret; token_hash is what's stored so a stolen
+// database dump can't be replayed as a live session.
+type RefreshToken struct {
+	ID        string
+	UserID    uint64
+	TokenHash string
+	ParentID  sql.NullString
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+	UserAgent string
+	IP        string
+}
+
+// SessionResponse is the user-facing view of a RefreshToken for GET
+// /auth/sessions. It deliberately omits TokenHash and ParentID - both are
+// internal linkage/secret material, not session metadata a user needs.
+type SessionResponse struct {
+	ID        string     `json:"id"`
+	IssuedAt  time.Time  `json:"issued_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+}
+
+// ToResponse converts a RefreshToken to its user-facing representation.
+func (t *RefreshToken) ToResponse() *SessionResponse {
+	resp := &SessionResponse{
+		ID:        t.ID,
+		IssuedAt:  t.IssuedAt,
+		ExpiresAt: t.ExpiresAt,
+		UserAgent: t.UserAgent,
+		IP:        t.IP,
+	}
+	if t.RevokedAt.Valid {
+		resp.RevokedAt = &t.RevokedAt.Time
+	}
+	return resp
+}
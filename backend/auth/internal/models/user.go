@@ -2,20 +2,48 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+// Login types supported by the users table
+const (
+	LoginTypePassword = "password"
+	LoginTypeOIDC     = "oidc"
+	LoginTypeGitHub   = "github"
+	LoginTypeGoogle   = "google"
+)
+
 // User represents a user in the system
 type User struct {
-	ID              uint64       `json:"id"`
-	Email           string       `json:"email"`
-	PasswordHash    string       `json:"-"` // Never expose password hash
-	Role            string       `json:"role"`
-	IsActive        bool         `json:"is_active"`
-	EmailVerifiedAt sql.NullTime `json:"email_verified_at,omitempty"`
-	LastLoginAt     sql.NullTime `json:"last_login_at,omitempty"`
-	CreatedAt       time.Time    `json:"created_at"`
-	UpdatedAt       time.Time    `json:"updated_at"`
+	ID                uint64         `json:"id"`
+	Email             string         `json:"email"`
+	PasswordHash      string         `json:"-"` // Never expose password hash
+	Role              string         `json:"role"`
+	LoginType         string         `json:"login_type"`
+	IsActive          bool           `json:"is_active"`
+	EmailVerifiedAt   sql.NullTime   `json:"email_verified_at,omitempty"`
+	LastLoginAt       sql.NullTime   `json:"last_login_at,omitempty"`
+	TOTPSecret        sql.NullString `json:"-"` // base32 TOTP secret; set once MFA is enrolled, regardless of MFAEnabled
+	MFAEnabled        bool           `json:"mfa_enabled"`
+	RecoveryCodesHash sql.NullString `json:"-"` // JSON array of bcrypt-hashed single-use recovery codes
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// RecoveryCodeHashes decodes the JSON-encoded RecoveryCodesHash column into
+// a slice, returning an empty slice if it's unset or malformed.
+func (u *User) RecoveryCodeHashes() []string {
+	if !u.RecoveryCodesHash.Valid || u.RecoveryCodesHash.String == "" {
+		return []string{}
+	}
+
+	var hashes []string
+	if err := json.Unmarshal([]byte(u.RecoveryCodesHash.String), &hashes); err != nil {
+		return []string{}
+	}
+
+	return hashes
 }
 
 // UserResponse is the public representation of a user
@@ -23,9 +51,11 @@ type UserResponse struct {
 	ID              uint64  `json:"id"`
 	Email           string  `json:"email"`
 	Role            string  `json:"role"`
+	LoginType       string  `json:"login_type"`
 	IsActive        bool    `json:"is_active"`
 	EmailVerifiedAt *string `json:"email_verified_at,omitempty"`
 	LastLoginAt     *string `json:"last_login_at,omitempty"`
+	MFAEnabled      bool    `json:"mfa_enabled"`
 	CreatedAt       string  `json:"created_at"`
 	UpdatedAt       string  `json:"updated_at"`
 }
@@ -33,12 +63,14 @@ type UserResponse struct {
 // ToResponse converts a User to UserResponse
 func (u *User) ToResponse() *UserResponse {
 	response := &UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: u.UpdatedAt.Format(time.RFC3339),
+		ID:         u.ID,
+		Email:      u.Email,
+		Role:       u.Role,
+		LoginType:  u.LoginType,
+		IsActive:   u.IsActive,
+		MFAEnabled: u.MFAEnabled,
+		CreatedAt:  u.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  u.UpdatedAt.Format(time.RFC3339),
 	}
 
 	if u.EmailVerifiedAt.Valid {
@@ -66,12 +98,17 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// LoginResponse represents a login response
+// LoginResponse represents a login response. When the account has MFA
+// enabled, Login returns MFARequired/MFAPendingToken instead of tokens, and
+// the caller must complete POST /auth/login/mfa to receive this same shape
+// with User/AccessToken/RefreshToken/ExpiresIn populated.
 type LoginResponse struct {
-	User         *UserResponse `json:"user"`
-	AccessToken  string        `json:"access_token"`
-	RefreshToken string        `json:"refresh_token"`
-	ExpiresIn    int64         `json:"expires_in"`
+	User            *UserResponse `json:"user,omitempty"`
+	AccessToken     string        `json:"access_token,omitempty"`
+	RefreshToken    string        `json:"refresh_token,omitempty"`
+	ExpiresIn       int64         `json:"expires_in,omitempty"`
+	MFARequired     bool          `json:"mfa_required,omitempty"`
+	MFAPendingToken string        `json:"mfa_pending_token,omitempty"`
 }
 
 // RefreshTokenRequest represents a refresh token request
@@ -88,4 +125,84 @@ type UpdateUserRequest struct {
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
-}
\ No newline at end of file
+}
+
+// UserFilter narrows GET /admin/users by exact or substring match, plus
+// pagination. A zero value matches every user. Page is 1-indexed.
+type UserFilter struct {
+	Email        string
+	Role         string
+	IsActive     *bool
+	CreatedAfter time.Time
+	Query        string // substring match against email
+	Page         int
+	PageSize     int
+}
+
+// AdminUpdateUserRequest changes a user's role and/or active status. Both
+// fields are optional so an admin can flip just one without resending the
+// other.
+type AdminUpdateUserRequest struct {
+	Role     string `json:"role" binding:"omitempty,oneof=user admin"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// AdminResetPasswordResponse returns a one-time password-reset token for an
+// admin to relay to the user out-of-band (e.g. a helpdesk workflow). Only
+// the token's hash is persisted; this is the only time it's available in
+// plaintext.
+type AdminResetPasswordResponse struct {
+	ResetToken string `json:"reset_token"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+// VerifyEmailRequest confirms an email address using the token from the
+// link sent by POST /auth/me/verify-email/request.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ForgotPasswordRequest starts the password-reset flow. The response is
+// always a success, whether or not the email belongs to an account, so
+// this endpoint can't be used to enumerate registered users.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest completes the password-reset flow using the token
+// from the link emailed by POST /auth/forgot-password.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// MFAEnrollResponse is returned by POST /auth/me/mfa/enroll. MFA isn't
+// active yet - the secret must be confirmed via POST /auth/me/mfa/verify
+// before MFAEnabled flips on. The recovery codes are shown in plaintext
+// exactly once; only their bcrypt hashes are persisted.
+type MFAEnrollResponse struct {
+	Secret          string   `json:"secret"`
+	OTPAuthURL      string   `json:"otpauth_url"`
+	QRCodePNGBase64 string   `json:"qr_code_png_base64"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+// MFAVerifyRequest activates MFA after enrollment with a live 6-digit TOTP
+// code from the enrolled secret.
+type MFAVerifyRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// MFADisableRequest re-confirms the account password before MFA can be
+// turned off.
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginMFARequest completes a login that returned an MFA challenge,
+// submitting the pending token from LoginResponse plus either a live TOTP
+// code or an unused recovery code.
+type LoginMFARequest struct {
+	PendingToken string `json:"mfa_pending_token" binding:"required"`
+	Code         string `json:"code" binding:"required"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserLink represents a linked external/social identity attached to a user,
+// one row per (user, login_type). OAuth token state lives here rather than
+// on the user or any session table so a user can hold multiple provider links.
+type UserLink struct {
+	ID                uint64       `json:"id"`
+	UserID            uint64       `json:"user_id"`
+	LoginType         string       `json:"login_type"`
+	LinkedUserID      string       `json:"linked_user_id"` // subject/ID from the provider
+	OAuthAccessToken  string       `json:"-"`
+	OAuthRefreshToken string       `json:"-"`
+	OAuthExpiry       sql.NullTime `json:"oauth_expiry,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+}
+
+// UserLinkResponse is the public representation of a UserLink
+type UserLinkResponse struct {
+	ID           uint64 `json:"id"`
+	LoginType    string `json:"login_type"`
+	LinkedUserID string `json:"linked_user_id"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// ToResponse converts a UserLink to UserLinkResponse
+func (l *UserLink) ToResponse() *UserLinkResponse {
+	return &UserLinkResponse{
+		ID:           l.ID,
+		LoginType:    l.LoginType,
+		LinkedUserID: l.LinkedUserID,
+		CreatedAt:    l.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -55,7 +55,7 @@ func (r *APIKeyRepository) Create(
 // FindByID finds an API key by ID
 func (r *APIKeyRepository) FindByID(ctx context.Context, id uint64) (*models.APIKey, error) {
 	query := `
-		SELECT id, user_id, name, key_prefix, key_hash, scopes, rate_limit, 
+		SELECT id, user_id, name, key_prefix, key_hash, old_key_hash, old_key_expires_at, scopes, rate_limit,
 		       total_requests, last_used_at, is_active, expires_at, created_at, updated_at
 		FROM api_keys
 		WHERE id = ?
@@ -68,6 +68,8 @@ func (r *APIKeyRepository) FindByID(ctx context.Context, id uint64) (*models.API
 		&apiKey.Name,
 		&apiKey.KeyPrefix,
 		&apiKey.KeyHash,
+		&apiKey.OldKeyHash,
+		&apiKey.OldKeyExpiresAt,
 		&apiKey.Scopes,
 		&apiKey.RateLimit,
 		&apiKey.TotalRequests,
@@ -91,7 +93,7 @@ func (r *APIKeyRepository) FindByID(ctx context.Context, id uint64) (*models.API
 // FindByUserID finds all API keys for a user
 func (r *APIKeyRepository) FindByUserID(ctx context.Context, userID uint64) ([]*models.APIKey, error) {
 	query := `
-		SELECT id, user_id, name, key_prefix, key_hash, scopes, rate_limit, 
+		SELECT id, user_id, name, key_prefix, key_hash, old_key_hash, old_key_expires_at, scopes, rate_limit,
 		       total_requests, last_used_at, is_active, expires_at, created_at, updated_at
 		FROM api_keys
 		WHERE user_id = ?
@@ -113,6 +115,8 @@ func (r *APIKeyRepository) FindByUserID(ctx context.Context, userID uint64) ([]*
 			&apiKey.Name,
 			&apiKey.KeyPrefix,
 			&apiKey.KeyHash,
+			&apiKey.OldKeyHash,
+			&apiKey.OldKeyExpiresAt,
 			&apiKey.Scopes,
 			&apiKey.RateLimit,
 			&apiKey.TotalRequests,
@@ -135,22 +139,27 @@ func (r *APIKeyRepository) FindByUserID(ctx context.Context, userID uint64) ([]*
 	return apiKeys, nil
 }
 
-// FindByKeyHash finds an API key by its hash
+// FindByKeyHash finds an API key by its hash. It also matches a key's
+// previous hash while it's within its post-rotation grace period, so a
+// caller holding the old secret keeps working until OldKeyExpiresAt.
 func (r *APIKeyRepository) FindByKeyHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
 	query := `
-		SELECT id, user_id, name, key_prefix, key_hash, scopes, rate_limit, 
+		SELECT id, user_id, name, key_prefix, key_hash, old_key_hash, old_key_expires_at, scopes, rate_limit,
 		       total_requests, last_used_at, is_active, expires_at, created_at, updated_at
 		FROM api_keys
-		WHERE key_hash = ? AND is_active = TRUE
+		WHERE is_active = TRUE
+		  AND (key_hash = ? OR (old_key_hash = ? AND old_key_expires_at > NOW()))
 	`
 
 	apiKey := &models.APIKey{}
-	err := r.db.QueryRowContext(ctx, query, keyHash).Scan(
+	err := r.db.QueryRowContext(ctx, query, keyHash, keyHash).Scan(
 		&apiKey.ID,
 		&apiKey.UserID,
 		&apiKey.Name,
 		&apiKey.KeyPrefix,
 		&apiKey.KeyHash,
+		&apiKey.OldKeyHash,
+		&apiKey.OldKeyExpiresAt,
 		&apiKey.Scopes,
 		&apiKey.RateLimit,
 		&apiKey.TotalRequests,
@@ -223,4 +232,116 @@ func (r *APIKeyRepository) UpdateRateLimit(ctx context.Context, id uint64, rateL
 
 	_, err := r.db.ExecContext(ctx, query, rateLimit, id)
 	return err
+}
+
+// Rotate replaces a key's secret hash, keeping the previous hash valid
+// until oldKeyExpiresAt so in-flight callers have a grace period to pick
+// up the new secret.
+func (r *APIKeyRepository) Rotate(ctx context.Context, id uint64, newKeyHash string, oldKeyHash string, oldKeyExpiresAt time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET key_hash = ?, old_key_hash = ?, old_key_expires_at = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, newKeyHash, oldKeyHash, oldKeyExpiresAt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// RecordUsageBatch applies a batch of usage increments gathered by the
+// gateway, one DB round trip per key rather than per request. It updates
+// the running total_requests/last_used_at on api_keys and rolls each
+// increment into its hourly bucket in api_key_usage for GET .../usage.
+func (r *APIKeyRepository) RecordUsageBatch(ctx context.Context, increments []models.APIKeyUsageIncrement) error {
+	if len(increments) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, inc := range increments {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE api_keys
+			SET total_requests = total_requests + ?, last_used_at = NOW(), updated_at = NOW()
+			WHERE id = ?
+		`, inc.Count, inc.KeyID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO api_key_usage (api_key_id, bucket, request_count)
+			VALUES (?, DATE_FORMAT(NOW(), '%Y-%m-%d %H:00:00'), ?)
+			ON DUPLICATE KEY UPDATE request_count = request_count + VALUES(request_count)
+		`, inc.KeyID, inc.Count); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindUsageBuckets returns hourly request-count buckets for keyID since the
+// given time, oldest first.
+func (r *APIKeyRepository) FindUsageBuckets(ctx context.Context, keyID uint64, since time.Time) ([]models.APIKeyUsageBucket, error) {
+	query := `
+		SELECT bucket, request_count
+		FROM api_key_usage
+		WHERE api_key_id = ? AND bucket >= ?
+		ORDER BY bucket ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, keyID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []models.APIKeyUsageBucket
+	for rows.Next() {
+		var bucket models.APIKeyUsageBucket
+		if err := rows.Scan(&bucket.Bucket, &bucket.RequestCount); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// SweepExpired deactivates every active key past its ExpiresAt and reports
+// how many rows it touched.
+func (r *APIKeyRepository) SweepExpired(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE api_keys
+		SET is_active = FALSE, updated_at = NOW()
+		WHERE is_active = TRUE AND expires_at IS NOT NULL AND expires_at <= NOW()
+	`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
 }
\ No newline at end of file
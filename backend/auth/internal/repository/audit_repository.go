@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/captcha-platform/auth/internal/models"
+)
+
+// AuditRepository handles database operations for the audit_events
+// compliance trail.
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create inserts a new audit event
+func (r *AuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (user_id, event_type, actor, ip, user_agent, request_id, metadata, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		event.UserID, event.EventType, event.Actor, event.IP, event.UserAgent, event.RequestID, event.Metadata,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.ID = uint64(id)
+
+	return nil
+}
+
+// FindByUserID returns audit events for a single user, newest first,
+// narrowed by filter.
+func (r *AuditRepository) FindByUserID(ctx context.Context, userID uint64, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	filter.UserID = &userID
+	return r.find(ctx, filter)
+}
+
+// FindAll returns audit events across all users, newest first, narrowed by
+// filter. Used by the admin audit endpoint.
+func (r *AuditRepository) FindAll(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	return r.find(ctx, filter)
+}
+
+// find builds and runs the filtered audit_events query shared by
+// FindByUserID and FindAll.
+func (r *AuditRepository) find(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	query := `
+		SELECT id, user_id, event_type, actor, ip, user_agent, request_id, metadata, created_at
+		FROM audit_events
+		WHERE 1 = 1
+	`
+	var args []interface{}
+
+	if filter.UserID != nil {
+		query += " AND user_id = ?"
+		args = append(args, *filter.UserID)
+	}
+	if filter.EventType != "" {
+		query += " AND event_type = ?"
+		args = append(args, filter.EventType)
+	}
+	if !filter.From.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		query += " AND created_at <= ?"
+		args = append(args, filter.To)
+	}
+
+	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	args = append(args, limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, strings.TrimSpace(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		event := &models.AuditEvent{}
+		if err := rows.Scan(
+			&event.ID, &event.UserID, &event.EventType, &event.Actor, &event.IP,
+			&event.UserAgent, &event.RequestID, &event.Metadata, &event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
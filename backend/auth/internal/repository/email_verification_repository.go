@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/captcha-platform/auth/internal/models"
+)
+
+var ErrEmailVerificationTokenNotFound = errors.New("email verification token not found")
+
+// EmailVerificationRepository handles database operations for
+// email_verification_tokens
+type EmailVerificationRepository struct {
+	db *sql.DB
+}
+
+// NewEmailVerificationRepository creates a new EmailVerificationRepository
+func NewEmailVerificationRepository(db *sql.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+// Create persists a new email verification token.
+func (r *EmailVerificationRepository) Create(ctx context.Context, id string, userID uint64, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO email_verification_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, NOW())
+	`
+	_, err := r.db.ExecContext(ctx, query, id, userID, tokenHash, expiresAt)
+	return err
+}
+
+// Consume atomically fetches and marks used an email verification token by
+// its hash, so the same link can't verify two different confirmations under
+// concurrent requests.
+func (r *EmailVerificationRepository) Consume(ctx context.Context, tokenHash string) (*models.EmailVerificationToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM email_verification_tokens
+		WHERE token_hash = ?
+		FOR UPDATE
+	`
+
+	token := &models.EmailVerificationToken{}
+	err = tx.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEmailVerificationTokenNotFound
+		}
+		return nil, err
+	}
+
+	if token.UsedAt.Valid {
+		return nil, ErrEmailVerificationTokenNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE email_verification_tokens SET used_at = NOW() WHERE id = ?`, token.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrEmailVerificationTokenNotFound
+	}
+
+	return token, nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/captcha-platform/auth/internal/models"
+)
+
+var (
+	ErrOIDCClientNotFound        = errors.New("oidc client not found")
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found or already used")
+	ErrOIDCRefreshTokenNotFound  = errors.New("oidc refresh token not found")
+)
+
+// OIDCRepository persists registered OIDC relying parties and the
+// single-use authorization codes issued to them.
+type OIDCRepository struct {
+	db *sql.DB
+}
+
+// NewOIDCRepository creates a new OIDCRepository.
+func NewOIDCRepository(db *sql.DB) *OIDCRepository {
+	return &OIDCRepository{db: db}
+}
+
+// FindClientByClientID looks up a registered relying party by its public
+// client_id.
+func (r *OIDCRepository) FindClientByClientID(ctx context.Context, clientID string) (*models.OIDCClient, error) {
+	query := `
+		SELECT id, client_id, client_secret_hash, name, redirect_uris, allowed_grant_types, allowed_scopes, created_at
+		FROM oidc_clients
+		WHERE client_id = ?
+	`
+
+	client := &models.OIDCClient{}
+	var secretHash sql.NullString
+	var redirectURIsJSON, grantTypesJSON, scopesJSON string
+
+	err := r.db.QueryRowContext(ctx, query, clientID).Scan(
+		&client.ID, &client.ClientID, &secretHash, &client.Name, &redirectURIsJSON, &grantTypesJSON, &scopesJSON, &client.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOIDCClientNotFound
+		}
+		return nil, err
+	}
+
+	client.ClientSecretHash = secretHash.String
+	if err := json.Unmarshal([]byte(redirectURIsJSON), &client.RedirectURIs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(grantTypesJSON), &client.AllowedGrantTypes); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopesJSON), &client.AllowedScopes); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// CreateClient registers a new OAuth2/OIDC relying party. clientSecretHash
+// is empty to register a public client that authenticates with PKCE alone.
+func (r *OIDCRepository) CreateClient(ctx context.Context, client *models.OIDCClient) error {
+	redirectURIsJSON, err := json.Marshal(client.RedirectURIs)
+	if err != nil {
+		return err
+	}
+	grantTypesJSON, err := json.Marshal(client.AllowedGrantTypes)
+	if err != nil {
+		return err
+	}
+	scopesJSON, err := json.Marshal(client.AllowedScopes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO oidc_clients
+			(client_id, client_secret_hash, name, redirect_uris, allowed_grant_types, allowed_scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+	`
+	secretHash := sql.NullString{String: client.ClientSecretHash, Valid: client.ClientSecretHash != ""}
+
+	result, err := r.db.ExecContext(ctx, query, client.ClientID, secretHash, client.Name, redirectURIsJSON, grantTypesJSON, scopesJSON)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	client.ID = uint64(id)
+	return nil
+}
+
+// CreateAuthorizationCode stores a newly issued authorization code.
+func (r *OIDCRepository) CreateAuthorizationCode(ctx context.Context, code *models.OIDCAuthorizationCode) error {
+	query := `
+		INSERT INTO oidc_authorization_codes
+			(code, client_id, user_id, redirect_uri, code_challenge, code_challenge_method, nonce, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		code.Code, code.ClientID, code.UserID, code.RedirectURI,
+		code.CodeChallenge, code.CodeChallengeMethod, code.Nonce, code.ExpiresAt,
+	)
+	return err
+}
+
+// ConsumeAuthorizationCode atomically fetches and deletes an authorization
+// code in one transaction, so it can never be exchanged twice even under
+// concurrent requests. The caller still needs to check ExpiresAt itself.
+func (r *OIDCRepository) ConsumeAuthorizationCode(ctx context.Context, codeValue string) (*models.OIDCAuthorizationCode, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT code, client_id, user_id, redirect_uri, code_challenge, code_challenge_method, nonce, expires_at
+		FROM oidc_authorization_codes
+		WHERE code = ?
+		FOR UPDATE
+	`
+
+	authCode := &models.OIDCAuthorizationCode{}
+	err = tx.QueryRowContext(ctx, query, codeValue).Scan(
+		&authCode.Code, &authCode.ClientID, &authCode.UserID, &authCode.RedirectURI,
+		&authCode.CodeChallenge, &authCode.CodeChallengeMethod, &authCode.Nonce, &authCode.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAuthorizationCodeNotFound
+		}
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM oidc_authorization_codes WHERE code = ?`, codeValue); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(authCode.ExpiresAt) {
+		return nil, ErrAuthorizationCodeNotFound
+	}
+
+	return authCode, nil
+}
+
+// CreateRefreshToken persists a newly issued OIDC refresh token. parentID is
+// non-nil when this token was issued by rotating a previous one.
+func (r *OIDCRepository) CreateRefreshToken(ctx context.Context, id, clientID string, userID uint64, tokenHash string, parentID *string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO oidc_refresh_tokens (id, client_id, user_id, token_hash, parent_id, issued_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, NOW(), ?)
+	`
+	_, err := r.db.ExecContext(ctx, query, id, clientID, userID, tokenHash, parentID, expiresAt)
+	return err
+}
+
+// FindRefreshTokenByHash finds an OIDC refresh token by the hash of its
+// opaque secret.
+func (r *OIDCRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.OIDCRefreshToken, error) {
+	query := `
+		SELECT id, client_id, user_id, token_hash, parent_id, expires_at, revoked_at
+		FROM oidc_refresh_tokens
+		WHERE token_hash = ?
+	`
+
+	token := &models.OIDCRefreshToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.ClientID, &token.UserID, &token.TokenHash, &token.ParentID, &token.ExpiresAt, &token.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOIDCRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RevokeRefreshToken marks a single OIDC refresh token as revoked.
+func (r *OIDCRepository) RevokeRefreshToken(ctx context.Context, id string) error {
+	query := `UPDATE oidc_refresh_tokens SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
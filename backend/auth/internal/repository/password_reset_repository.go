@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/captcha-platform/auth/internal/models"
+)
+
+var ErrPasswordResetTokenNotFound = errors.New("password reset token not found")
+
+// PasswordResetRepository handles database operations for password_reset_tokens
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository creates a new PasswordResetRepository
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create persists a new password reset token.
+func (r *PasswordResetRepository) Create(ctx context.Context, id string, userID uint64, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, NOW())
+	`
+	_, err := r.db.ExecContext(ctx, query, id, userID, tokenHash, expiresAt)
+	return err
+}
+
+// Consume atomically fetches and marks used a password reset token by its
+// hash, so the same token can never set two different passwords even under
+// concurrent requests.
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = ?
+		FOR UPDATE
+	`
+
+	token := &models.PasswordResetToken{}
+	err = tx.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPasswordResetTokenNotFound
+		}
+		return nil, err
+	}
+
+	if token.UsedAt.Valid {
+		return nil, ErrPasswordResetTokenNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE password_reset_tokens SET used_at = NOW() WHERE id = ?`, token.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrPasswordResetTokenNotFound
+	}
+
+	return token, nil
+}
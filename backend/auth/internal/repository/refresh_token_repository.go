@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/captcha-platform/auth/internal/models"
+)
+
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+var ErrRefreshTokenReused = errors.New("refresh token already rotated")
+
+// RefreshTokenRepository handles database operations for persisted refresh tokens
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+// NewRefreshTokenRepository creates a new RefreshTokenRepository
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create persists a new refresh token. parentID is non-nil when this token
+// was issued by rotating a previous one.
+func (r *RefreshTokenRepository) Create(
+	ctx context.Context,
+	id string,
+	userID uint64,
+	tokenHash string,
+	parentID *string,
+	expiresAt time.Time,
+	userAgent, ip string,
+) (*models.RefreshToken, error) {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, token_hash, parent_id, issued_at, expires_at, user_agent, ip)
+		VALUES (?, ?, ?, ?, NOW(), ?, ?, ?)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, userID, tokenHash, parentID, expiresAt, userAgent, ip); err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+// FindByID finds a refresh token by ID
+func (r *RefreshTokenRepository) FindByID(ctx context.Context, id string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, parent_id, issued_at, expires_at, revoked_at, user_agent, ip
+		FROM refresh_tokens
+		WHERE id = ?
+	`
+	return r.scanOne(r.db.QueryRowContext(ctx, query, id))
+}
+
+// FindByTokenHash finds a refresh token by the hash of its opaque secret
+func (r *RefreshTokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, parent_id, issued_at, expires_at, revoked_at, user_agent, ip
+		FROM refresh_tokens
+		WHERE token_hash = ?
+	`
+	return r.scanOne(r.db.QueryRowContext(ctx, query, tokenHash))
+}
+
+func (r *RefreshTokenRepository) scanOne(row *sql.Row) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ParentID,
+		&token.IssuedAt,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.UserAgent,
+		&token.IP,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// FindActiveByUserID lists a user's non-revoked, non-expired sessions, most
+// recently issued first, for GET /auth/sessions.
+func (r *RefreshTokenRepository) FindActiveByUserID(ctx context.Context, userID uint64) ([]*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, parent_id, issued_at, expires_at, revoked_at, user_agent, ip
+		FROM refresh_tokens
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY issued_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.RefreshToken
+	for rows.Next() {
+		token := &models.RefreshToken{}
+		if err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.TokenHash,
+			&token.ParentID,
+			&token.IssuedAt,
+			&token.ExpiresAt,
+			&token.RevokedAt,
+			&token.UserAgent,
+			&token.IP,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Rotate atomically replaces the refresh token identified by oldTokenHash
+// with a newly issued one: it locks the old row, rejects it if already
+// revoked or expired, inserts the replacement (with parent_id set to the old
+// row's id, continuing the rotation chain), and revokes the old row, all in
+// one transaction. Without the lock, two concurrent requests presenting the
+// same still-valid token could both pass the revoked check before either
+// marked it revoked, minting two live token families from one secret.
+// Returns the old token (so the caller can read its UserID) and
+// ErrRefreshTokenReused if it was already rotated - the caller should treat
+// that as theft and burn the whole session family.
+func (r *RefreshTokenRepository) Rotate(
+	ctx context.Context,
+	oldTokenHash string,
+	newID string,
+	newTokenHash string,
+	newExpiresAt time.Time,
+	userAgent, ip string,
+) (*models.RefreshToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, user_id, token_hash, parent_id, issued_at, expires_at, revoked_at, user_agent, ip
+		FROM refresh_tokens
+		WHERE token_hash = ?
+		FOR UPDATE
+	`
+
+	old := &models.RefreshToken{}
+	err = tx.QueryRowContext(ctx, query, oldTokenHash).Scan(
+		&old.ID,
+		&old.UserID,
+		&old.TokenHash,
+		&old.ParentID,
+		&old.IssuedAt,
+		&old.ExpiresAt,
+		&old.RevokedAt,
+		&old.UserAgent,
+		&old.IP,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	if old.RevokedAt.Valid {
+		return old, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(old.ExpiresAt) {
+		return old, ErrRefreshTokenNotFound
+	}
+
+	insert := `
+		INSERT INTO refresh_tokens (id, user_id, token_hash, parent_id, issued_at, expires_at, user_agent, ip)
+		VALUES (?, ?, ?, ?, NOW(), ?, ?, ?)
+	`
+	if _, err := tx.ExecContext(ctx, insert, newID, old.UserID, newTokenHash, old.ID, newExpiresAt, userAgent, ip); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = ?`, old.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return old, nil
+}
+
+// Revoke marks a single refresh token as revoked
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// RevokeAllForUser revokes every active refresh token belonging to a user.
+// Used both for single-session-mode logins and for theft response when a
+// rotated (already-revoked) token is replayed.
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uint64) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/captcha-platform/auth/internal/models"
+)
+
+var (
+	ErrUserLinkNotFound      = errors.New("user link not found")
+	ErrUserLinkAlreadyExists = errors.New("this provider account is already linked")
+)
+
+// UserLinkRepository handles database operations for linked external identities
+type UserLinkRepository struct {
+	db *sql.DB
+}
+
+// NewUserLinkRepository creates a new UserLinkRepository
+func NewUserLinkRepository(db *sql.DB) *UserLinkRepository {
+	return &UserLinkRepository{db: db}
+}
+
+// Create creates a new user link
+func (r *UserLinkRepository) Create(
+	ctx context.Context,
+	userID uint64,
+	loginType, linkedUserID, accessToken, refreshToken string,
+	expiry *time.Time,
+) (*models.UserLink, error) {
+	query := `
+		INSERT INTO user_links (user_id, login_type, linked_user_id, oauth_access_token, oauth_refresh_token, oauth_expiry, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, loginType, linkedUserID, accessToken, refreshToken, expiry)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrUserLinkAlreadyExists
+		}
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, uint64(id))
+}
+
+// FindByID finds a user link by ID
+func (r *UserLinkRepository) FindByID(ctx context.Context, id uint64) (*models.UserLink, error) {
+	query := `
+		SELECT id, user_id, login_type, linked_user_id, oauth_access_token, oauth_refresh_token, oauth_expiry, created_at, updated_at
+		FROM user_links
+		WHERE id = ?
+	`
+
+	link := &models.UserLink{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&link.ID,
+		&link.UserID,
+		&link.LoginType,
+		&link.LinkedUserID,
+		&link.OAuthAccessToken,
+		&link.OAuthRefreshToken,
+		&link.OAuthExpiry,
+		&link.CreatedAt,
+		&link.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserLinkNotFound
+		}
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// FindByProvider finds a user link by login type and the provider's user ID,
+// used to resolve an incoming OAuth callback to an existing local user.
+func (r *UserLinkRepository) FindByProvider(ctx context.Context, loginType, linkedUserID string) (*models.UserLink, error) {
+	query := `
+		SELECT id, user_id, login_type, linked_user_id, oauth_access_token, oauth_refresh_token, oauth_expiry, created_at, updated_at
+		FROM user_links
+		WHERE login_type = ? AND linked_user_id = ?
+	`
+
+	link := &models.UserLink{}
+	err := r.db.QueryRowContext(ctx, query, loginType, linkedUserID).Scan(
+		&link.ID,
+		&link.UserID,
+		&link.LoginType,
+		&link.LinkedUserID,
+		&link.OAuthAccessToken,
+		&link.OAuthRefreshToken,
+		&link.OAuthExpiry,
+		&link.CreatedAt,
+		&link.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserLinkNotFound
+		}
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// FindByUserID finds all links for a user
+func (r *UserLinkRepository) FindByUserID(ctx context.Context, userID uint64) ([]*models.UserLink, error) {
+	query := `
+		SELECT id, user_id, login_type, linked_user_id, oauth_access_token, oauth_refresh_token, oauth_expiry, created_at, updated_at
+		FROM user_links
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*models.UserLink
+	for rows.Next() {
+		link := &models.UserLink{}
+		err := rows.Scan(
+			&link.ID,
+			&link.UserID,
+			&link.LoginType,
+			&link.LinkedUserID,
+			&link.OAuthAccessToken,
+			&link.OAuthRefreshToken,
+			&link.OAuthExpiry,
+			&link.CreatedAt,
+			&link.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// UpdateTokens updates the stored OAuth tokens for a link after a refresh
+func (r *UserLinkRepository) UpdateTokens(ctx context.Context, id uint64, accessToken, refreshToken string, expiry *time.Time) error {
+	query := `
+		UPDATE user_links
+		SET oauth_access_token = ?, oauth_refresh_token = ?, oauth_expiry = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, accessToken, refreshToken, expiry, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserLinkNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a link, unlinking the provider from the user
+func (r *UserLinkRepository) Delete(ctx context.Context, id uint64, userID uint64) error {
+	query := `DELETE FROM user_links WHERE id = ? AND user_id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserLinkNotFound
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/captcha-platform/auth/internal/models"
@@ -27,11 +28,11 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, email, passwordHash string) (*models.User, error) {
 	query := `
-		INSERT INTO users (email, password_hash, role, is_active, created_at, updated_at)
-		VALUES (?, ?, 'user', TRUE, NOW(), NOW())
+		INSERT INTO users (email, password_hash, role, login_type, is_active, created_at, updated_at)
+		VALUES (?, ?, 'user', ?, TRUE, NOW(), NOW())
 	`
 
-	result, err := r.db.ExecContext(ctx, query, email, passwordHash)
+	result, err := r.db.ExecContext(ctx, query, email, passwordHash, models.LoginTypePassword)
 	if err != nil {
 		// Check for duplicate entry error
 		if isDuplicateKeyError(err) {
@@ -48,10 +49,35 @@ func (r *UserRepository) Create(ctx context.Context, email, passwordHash string)
 	return r.FindByID(ctx, uint64(id))
 }
 
+// CreateWithLoginType creates a new user provisioned via an external login method
+// (e.g. OIDC/social), where there is no password to store.
+func (r *UserRepository) CreateWithLoginType(ctx context.Context, email, loginType string) (*models.User, error) {
+	query := `
+		INSERT INTO users (email, password_hash, role, login_type, is_active, created_at, updated_at)
+		VALUES (?, '', 'user', ?, TRUE, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query, email, loginType)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrUserAlreadyExists
+		}
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, uint64(id))
+}
+
 // FindByID finds a user by ID
 func (r *UserRepository) FindByID(ctx context.Context, id uint64) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, role, is_active, email_verified_at, last_login_at, created_at, updated_at
+		SELECT id, email, password_hash, role, login_type, is_active, email_verified_at, last_login_at,
+		       totp_secret, mfa_enabled, recovery_codes_hash, created_at, updated_at
 		FROM users
 		WHERE id = ?
 	`
@@ -62,9 +88,13 @@ func (r *UserRepository) FindByID(ctx context.Context, id uint64) (*models.User,
 		&user.Email,
 		&user.PasswordHash,
 		&user.Role,
+		&user.LoginType,
 		&user.IsActive,
 		&user.EmailVerifiedAt,
 		&user.LastLoginAt,
+		&user.TOTPSecret,
+		&user.MFAEnabled,
+		&user.RecoveryCodesHash,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -82,7 +112,8 @@ func (r *UserRepository) FindByID(ctx context.Context, id uint64) (*models.User,
 // FindByEmail finds a user by email
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, role, is_active, email_verified_at, last_login_at, created_at, updated_at
+		SELECT id, email, password_hash, role, login_type, is_active, email_verified_at, last_login_at,
+		       totp_secret, mfa_enabled, recovery_codes_hash, created_at, updated_at
 		FROM users
 		WHERE email = ?
 	`
@@ -93,9 +124,13 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models
 		&user.Email,
 		&user.PasswordHash,
 		&user.Role,
+		&user.LoginType,
 		&user.IsActive,
 		&user.EmailVerifiedAt,
 		&user.LastLoginAt,
+		&user.TOTPSecret,
+		&user.MFAEnabled,
+		&user.RecoveryCodesHash,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -155,6 +190,25 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, id uint64, password
 	return nil
 }
 
+// SetEmailVerified stamps a user's email_verified_at with the current time.
+func (r *UserRepository) SetEmailVerified(ctx context.Context, id uint64) error {
+	query := `UPDATE users SET email_verified_at = NOW(), updated_at = NOW() WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
 // UpdateLastLogin updates a user's last login time
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, id uint64) error {
 	query := `UPDATE users SET last_login_at = NOW(), updated_at = NOW() WHERE id = ?`
@@ -163,6 +217,154 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, id uint64) error {
 	return err
 }
 
+// SetPendingMFA stores a freshly-enrolled TOTP secret and recovery code
+// hashes without turning MFA on yet - it only takes effect once the secret
+// is confirmed via ActivateMFA.
+func (r *UserRepository) SetPendingMFA(ctx context.Context, id uint64, totpSecret, recoveryCodesHashJSON string) error {
+	query := `UPDATE users SET totp_secret = ?, recovery_codes_hash = ?, updated_at = NOW() WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, totpSecret, recoveryCodesHashJSON, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ActivateMFA turns MFA on for a user that already has a pending secret
+// set by SetPendingMFA.
+func (r *UserRepository) ActivateMFA(ctx context.Context, id uint64) error {
+	query := `UPDATE users SET mfa_enabled = TRUE, updated_at = NOW() WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// DisableMFA turns MFA off and clears the secret and recovery codes, so
+// re-enrolling starts from a clean slate.
+func (r *UserRepository) DisableMFA(ctx context.Context, id uint64) error {
+	query := `UPDATE users SET mfa_enabled = FALSE, totp_secret = NULL, recovery_codes_hash = NULL, updated_at = NOW() WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateRecoveryCodesHash replaces a user's recovery code hashes, used after
+// one is consumed during an MFA login.
+func (r *UserRepository) UpdateRecoveryCodesHash(ctx context.Context, id uint64, recoveryCodesHashJSON string) error {
+	query := `UPDATE users SET recovery_codes_hash = ?, updated_at = NOW() WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, recoveryCodesHashJSON, id)
+	return err
+}
+
+// ConsumeRecoveryCode locks a user's recovery_codes_hash row, hands the
+// current hashes to match (the bcrypt comparison itself is the caller's
+// business logic, not the repository's), and persists whatever it returns
+// in the same transaction - so two concurrent MFA attempts can never both
+// succeed with the same recovery code.
+func (r *UserRepository) ConsumeRecoveryCode(ctx context.Context, id uint64, match func(hashesJSON string) (remainingJSON string, ok bool)) (bool, error) {
+	var consumed bool
+
+	err := r.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var hashesJSON sql.NullString
+		if err := tx.QueryRowContext(ctx, `SELECT recovery_codes_hash FROM users WHERE id = ? FOR UPDATE`, id).Scan(&hashesJSON); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		remainingJSON, ok := match(hashesJSON.String)
+		if !ok {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET recovery_codes_hash = ?, updated_at = NOW() WHERE id = ?`, remainingJSON, id); err != nil {
+			return err
+		}
+		consumed = true
+		return nil
+	})
+
+	return consumed, err
+}
+
+// UpdateRole changes a user's role (admin use only - there's no self-serve
+// path to grant yourself admin).
+func (r *UserRepository) UpdateRole(ctx context.Context, id uint64, role string) error {
+	query := `UPDATE users SET role = ?, updated_at = NOW() WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, role, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetActive activates or deactivates a user. Unlike Delete, this is
+// reversible - an admin can also use it to reinstate a soft-deleted account.
+func (r *UserRepository) SetActive(ctx context.Context, id uint64, active bool) error {
+	query := `UPDATE users SET is_active = ?, updated_at = NOW() WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, active, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
 // Delete soft-deletes a user by deactivating them
 func (r *UserRepository) Delete(ctx context.Context, id uint64) error {
 	query := `UPDATE users SET is_active = FALSE, updated_at = NOW() WHERE id = ?`
@@ -221,7 +423,8 @@ func (r *UserRepository) List(ctx context.Context, offset, limit int) ([]*models
 
 	// Get users
 	query := `
-		SELECT id, email, password_hash, role, is_active, email_verified_at, last_login_at, created_at, updated_at
+		SELECT id, email, password_hash, role, login_type, is_active, email_verified_at, last_login_at,
+		       totp_secret, mfa_enabled, recovery_codes_hash, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
@@ -241,9 +444,13 @@ func (r *UserRepository) List(ctx context.Context, offset, limit int) ([]*models
 			&user.Email,
 			&user.PasswordHash,
 			&user.Role,
+			&user.LoginType,
 			&user.IsActive,
 			&user.EmailVerifiedAt,
 			&user.LastLoginAt,
+			&user.TOTPSecret,
+			&user.MFAEnabled,
+			&user.RecoveryCodesHash,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -260,6 +467,100 @@ func (r *UserRepository) List(ctx context.Context, offset, limit int) ([]*models
 	return users, total, nil
 }
 
+// Search returns a filtered, paginated list of users for GET /admin/users.
+// The WHERE clause is built from parameterized fragments - never string
+// concatenation of values - so a filter value can't break out of its
+// placeholder.
+func (r *UserRepository) Search(ctx context.Context, filter models.UserFilter) ([]*models.User, int64, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Email != "" {
+		conditions = append(conditions, "email = ?")
+		args = append(args, filter.Email)
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, "email LIKE ?")
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, filter.Role)
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, "is_active = ?")
+		args = append(args, *filter.IsActive)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > ?")
+		args = append(args, filter.CreatedAfter)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM users" + where
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page, pageSize := filter.Page, filter.PageSize
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT id, email, password_hash, role, login_type, is_active, email_verified_at, last_login_at,
+		       totp_secret, mfa_enabled, recovery_codes_hash, created_at, updated_at
+		FROM users` + where + `
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`
+	queryArgs := append(append([]interface{}{}, args...), pageSize, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.PasswordHash,
+			&user.Role,
+			&user.LoginType,
+			&user.IsActive,
+			&user.EmailVerifiedAt,
+			&user.LastLoginAt,
+			&user.TOTPSecret,
+			&user.MFAEnabled,
+			&user.RecoveryCodesHash,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards in a user-supplied substring so
+// "50%" or "a_b" match literally instead of as wildcard patterns.
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(s)
+}
+
 // Helper function to check for duplicate key error
 func isDuplicateKeyError(err error) bool {
 	return err != nil && (contains(err.Error(), "Duplicate entry") || contains(err.Error(), "1062"))
@@ -304,4 +605,4 @@ func (r *UserRepository) WithTransaction(ctx context.Context, fn TxFn) error {
 }
 
 // Ensure the struct implements necessary time operations
-var _ = time.Now
\ No newline at end of file
+var _ = time.Now
@@ -0,0 +1,26 @@
+package repository
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no special chars", "alice", "alice"},
+		{"percent wildcard", "50%off", `50\%off`},
+		{"underscore wildcard", "a_b", `a\_b`},
+		{"literal backslash", `a\b`, `a\\b`},
+		{"backslash must escape first to avoid double-escaping", `a\%b`, `a\\\%b`},
+		{"mixed injection attempt", `%_\`, `\%\_\\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeLike(tt.input); got != tt.want {
+				t.Errorf("escapeLike(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/captcha-platform/auth/internal/models"
+	"github.com/captcha-platform/auth/internal/repository"
+)
+
+// AuditEventInput is everything AuditLogger.Log needs to record one
+// compliance-trail entry. UserID is nil when the actor couldn't be
+// resolved to an account (e.g. a failed login against an unknown email).
+type AuditEventInput struct {
+	UserID    *uint64
+	EventType string
+	Actor     string
+	IP        string
+	UserAgent string
+	RequestID string
+	Metadata  map[string]interface{}
+}
+
+// AuditLogger writes structured audit_events rows for security-relevant
+// actions (login attempts, password changes, API key lifecycle, MFA
+// enrollment, ...), giving the compliance and incident-response side a
+// forensic trail independent of the application logs.
+type AuditLogger struct {
+	repo *repository.AuditRepository
+}
+
+// NewAuditLogger creates a new AuditLogger
+func NewAuditLogger(repo *repository.AuditRepository) *AuditLogger {
+	return &AuditLogger{repo: repo}
+}
+
+// Log records one audit event. Failures are the caller's to decide how to
+// handle (typically just logged, never surfaced to the end user) since a
+// missed audit write shouldn't fail the request that triggered it.
+func (l *AuditLogger) Log(ctx context.Context, in AuditEventInput) error {
+	event := &models.AuditEvent{
+		EventType: in.EventType,
+		Actor:     in.Actor,
+		IP:        in.IP,
+		UserAgent: in.UserAgent,
+		RequestID: in.RequestID,
+	}
+
+	if in.UserID != nil {
+		event.UserID = sql.NullInt64{Int64: int64(*in.UserID), Valid: true}
+	}
+
+	if len(in.Metadata) > 0 {
+		encoded, err := json.Marshal(in.Metadata)
+		if err == nil {
+			event.Metadata = sql.NullString{String: string(encoded), Valid: true}
+		}
+	}
+
+	return l.repo.Create(ctx, event)
+}
+
+// ListForUser returns audit events for a single user, used by GET
+// /auth/me/audit.
+func (l *AuditLogger) ListForUser(ctx context.Context, userID uint64, filter models.AuditFilter) ([]*models.AuditEventResponse, error) {
+	events, err := l.repo.FindByUserID(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return toAuditResponses(events), nil
+}
+
+// ListAll returns audit events across all users, used by GET /admin/audit.
+func (l *AuditLogger) ListAll(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEventResponse, error) {
+	events, err := l.repo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return toAuditResponses(events), nil
+}
+
+func toAuditResponses(events []*models.AuditEvent) []*models.AuditEventResponse {
+	responses := make([]*models.AuditEventResponse, len(events))
+	for i, event := range events {
+		responses[i] = event.ToResponse()
+	}
+	return responses
+}
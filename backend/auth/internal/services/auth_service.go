@@ -4,52 +4,158 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/captcha-platform/auth/internal/config"
 	"github.com/captcha-platform/auth/internal/models"
 	"github.com/captcha-platform/auth/internal/repository"
 	"github.com/captcha-platform/auth/pkg/jwt"
+	"github.com/captcha-platform/auth/pkg/mailer"
+	"github.com/captcha-platform/auth/pkg/scopes"
+	"github.com/captcha-platform/auth/pkg/totp"
 
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrUserNotActive      = errors.New("user account is not active")
-	ErrInvalidToken       = errors.New("invalid or expired token")
-	ErrMaxAPIKeysReached  = errors.New("maximum number of API keys reached")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrUserNotActive       = errors.New("user account is not active")
+	ErrInvalidToken        = errors.New("invalid or expired token")
+	ErrMaxAPIKeysReached   = errors.New("maximum number of API keys reached")
+	ErrLoginMethodMismatch = errors.New("this email is already registered with a different login method")
+	ErrInvalidScope        = errors.New("requested scope is not in the canonical scope vocabulary")
+	ErrAPIKeyNotOwned      = errors.New("API key does not belong to this user")
+	ErrMFANotEnabled       = errors.New("MFA is not enabled for this account")
+	ErrMFANotPending       = errors.New("no pending MFA enrollment to verify")
+	ErrInvalidMFACode      = errors.New("invalid or expired MFA code")
+	ErrOAuthEmailMissing   = errors.New("identity provider did not return a verified email; sign in with an existing account first to link this provider")
 )
 
 const (
-	MaxAPIKeysPerUser = 10
+	MaxAPIKeysPerUser  = 10
+	recoveryCodeLength = 10
+	passwordResetTTL   = time.Hour
 )
 
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo   *repository.UserRepository
-	apiKeyRepo *repository.APIKeyRepository
-	jwtConfig  config.JWTConfig
-	bcryptCost int
+	userRepo              *repository.UserRepository
+	apiKeyRepo            *repository.APIKeyRepository
+	userLinkRepo          *repository.UserLinkRepository
+	refreshTokenRepo      *repository.RefreshTokenRepository
+	passwordResetRepo     *repository.PasswordResetRepository
+	emailVerificationRepo *repository.EmailVerificationRepository
+	jwtConfig             config.JWTConfig
+	keyManager            *jwt.KeyManager // non-nil only when jwtConfig.SigningMethod is RS256
+	tokenValidator        *jwt.Validator  // non-nil only when Redis is available; backs RevokeToken/RevokeAllSessions
+	oidcConfig            config.OIDCConfig
+	enableMultiLogin      bool
+	bcryptCost            int
+	apiKeyConfig          config.APIKeyConfig
+	mfaConfig             config.MFAConfig
+	emailConfig           config.EmailConfig
+	mailer                mailer.Sender        // non-nil only when cfg.Email.SMTPHost is set; backs RequestEmailVerification/ForgotPassword
+	loginAttempts         *LoginAttemptTracker // backs Login's progressive lockout; a no-op tracker when Redis is unavailable
+	auditLogger           *AuditLogger
 }
 
 // NewAuthService creates a new AuthService
 func NewAuthService(
 	userRepo *repository.UserRepository,
 	apiKeyRepo *repository.APIKeyRepository,
+	userLinkRepo *repository.UserLinkRepository,
+	refreshTokenRepo *repository.RefreshTokenRepository,
+	passwordResetRepo *repository.PasswordResetRepository,
+	emailVerificationRepo *repository.EmailVerificationRepository,
 	jwtConfig config.JWTConfig,
+	keyManager *jwt.KeyManager,
+	tokenValidator *jwt.Validator,
+	oidcConfig config.OIDCConfig,
+	securityConfig config.SecurityConfig,
 	bcryptConfig config.BCryptConfig,
+	apiKeyConfig config.APIKeyConfig,
+	mfaConfig config.MFAConfig,
+	emailConfig config.EmailConfig,
+	mailSender mailer.Sender,
+	loginAttempts *LoginAttemptTracker,
+	auditLogger *AuditLogger,
 ) *AuthService {
 	return &AuthService{
-		userRepo:   userRepo,
-		apiKeyRepo: apiKeyRepo,
-		jwtConfig:  jwtConfig,
-		bcryptCost: bcryptConfig.Cost,
+		userRepo:              userRepo,
+		apiKeyRepo:            apiKeyRepo,
+		userLinkRepo:          userLinkRepo,
+		refreshTokenRepo:      refreshTokenRepo,
+		passwordResetRepo:     passwordResetRepo,
+		emailVerificationRepo: emailVerificationRepo,
+		jwtConfig:             jwtConfig,
+		keyManager:            keyManager,
+		tokenValidator:        tokenValidator,
+		oidcConfig:            oidcConfig,
+		enableMultiLogin:      securityConfig.EnableMultiLogin,
+		bcryptCost:            bcryptConfig.Cost,
+		apiKeyConfig:          apiKeyConfig,
+		mfaConfig:             mfaConfig,
+		emailConfig:           emailConfig,
+		mailer:                mailSender,
+		loginAttempts:         loginAttempts,
+		auditLogger:           auditLogger,
 	}
 }
 
+// recordAudit logs an audit event, swallowing the error beyond a best-effort
+// attempt - services.AuthService methods shouldn't fail the caller's request
+// just because the audit trail couldn't be written.
+func (s *AuthService) recordAudit(ctx context.Context, in AuditEventInput) {
+	if s.auditLogger == nil {
+		return
+	}
+	_ = s.auditLogger.Log(ctx, in)
+}
+
+// recordFailedLogin counts a failed login attempt toward the (email, ip)
+// pair's progressive lockout and logs a login.failure audit event. userID
+// is unknown here (the email may not even belong to an account), so the
+// event is attributed by the email actor alone.
+func (s *AuthService) recordFailedLogin(ctx context.Context, email, ip, userAgent, requestID string) {
+	if s.loginAttempts != nil {
+		_ = s.loginAttempts.RecordFailure(ctx, email, ip)
+	}
+	s.recordAudit(ctx, AuditEventInput{
+		EventType: models.AuditEventLoginFailure,
+		Actor:     email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+	})
+}
+
+// parseAccessToken decodes an access token's claims without enforcing the
+// idle timeout or revocation, so RevokeToken can read a token's jti and
+// expiry even if it's already been touched or is about to be revoked.
+func (s *AuthService) parseAccessToken(accessToken string) (*jwt.AccessClaims, error) {
+	if s.jwtConfig.SigningMethod == "RS256" && s.keyManager != nil {
+		return jwt.ValidateAccessTokenRS(accessToken, s.keyManager)
+	}
+	return jwt.ValidateAccessToken(accessToken, s.jwtConfig.Secret)
+}
+
+// generateAccessToken signs an access token for user, using RS256 via the
+// key manager when asymmetric signing is configured and falling back to the
+// shared HS256 secret otherwise.
+func (s *AuthService) generateAccessToken(user *models.User) (string, error) {
+	if s.jwtConfig.SigningMethod == "RS256" && s.keyManager != nil {
+		return jwt.GenerateAccessTokenRS(s.keyManager, user.ID, user.Email, user.Role, s.jwtConfig.Issuer, s.jwtConfig.ExpiresIn)
+	}
+	return jwt.GenerateAccessToken(user.ID, user.Email, user.Role, s.jwtConfig.Secret, s.jwtConfig.ExpiresIn)
+}
+
 // Register registers a new user
 func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.User, error) {
 	// Hash password
@@ -68,11 +174,18 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 }
 
 // Login authenticates a user and returns tokens
-func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, error) {
+func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, userAgent, ip, requestID string) (*models.LoginResponse, error) {
+	if s.loginAttempts != nil {
+		if retryAfter, err := s.loginAttempts.Locked(ctx, req.Email, ip); err == nil && retryAfter > 0 {
+			return nil, &LoginLockedError{RetryAfter: retryAfter}
+		}
+	}
+
 	// Find user by email
 	user, err := s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
+			s.recordFailedLogin(ctx, req.Email, ip, userAgent, requestID)
 			return nil, ErrInvalidCredentials
 		}
 		return nil, err
@@ -85,16 +198,53 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.recordFailedLogin(ctx, req.Email, ip, userAgent, requestID)
 		return nil, ErrInvalidCredentials
 	}
 
+	// The password check passed, so the caller has proven they know the
+	// credentials - reset the lockout tracker and log the success here even
+	// for an MFA-enabled account, since a second factor is a separate check
+	// and bypassing it isn't what the lockout is meant to prevent.
+	if s.loginAttempts != nil {
+		_ = s.loginAttempts.Reset(ctx, req.Email, ip)
+	}
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &user.ID,
+		EventType: models.AuditEventLoginSuccess,
+		Actor:     user.Email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+	})
+
+	// MFA-enabled accounts don't get tokens from a bare password check - the
+	// caller must complete POST /auth/login/mfa with this pending token and
+	// a live TOTP (or recovery) code.
+	if user.MFAEnabled {
+		pendingToken, err := jwt.GenerateMFAPendingToken(user.ID, s.jwtConfig.Secret, s.mfaConfig.PendingTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResponse{
+			MFARequired:     true,
+			MFAPendingToken: pendingToken,
+		}, nil
+	}
+
 	// Generate tokens
-	accessToken, err := jwt.GenerateAccessToken(user.ID, user.Email, user.Role, s.jwtConfig.Secret, s.jwtConfig.ExpiresIn)
+	accessToken, err := s.generateAccessToken(user)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := jwt.GenerateRefreshToken(user.ID, s.jwtConfig.Secret, s.jwtConfig.RefreshExpiresIn)
+	// Single-session mode: a fresh login revokes every other active session
+	// for this user before minting the new one.
+	if !s.enableMultiLogin {
+		_ = s.RevokeAllSessions(ctx, user.ID)
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID, nil, userAgent, ip)
 	if err != nil {
 		return nil, err
 	}
@@ -110,45 +260,441 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	}, nil
 }
 
-// RefreshToken refreshes an access token using a refresh token
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
-	// Validate refresh token
-	claims, err := jwt.ValidateRefreshToken(refreshToken, s.jwtConfig.Secret)
+// VerifyLoginMFA completes a Login that returned an MFA challenge: it
+// validates the pending token and the caller's TOTP (or recovery) code,
+// then issues tokens exactly like a normal Login.
+func (s *AuthService) VerifyLoginMFA(ctx context.Context, req *models.LoginMFARequest, userAgent, ip string) (*models.LoginResponse, error) {
+	claims, err := jwt.ValidateMFAPendingToken(req.PendingToken, s.jwtConfig.Secret)
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
 
-	// Get user
 	user, err := s.userRepo.FindByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if user is active
 	if !user.IsActive {
 		return nil, ErrUserNotActive
 	}
 
-	// Generate new access token
-	accessToken, err := jwt.GenerateAccessToken(user.ID, user.Email, user.Role, s.jwtConfig.Secret, s.jwtConfig.ExpiresIn)
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	if !s.consumeMFACode(ctx, user, req.Code) {
+		return nil, ErrInvalidMFACode
+	}
+
+	if !s.enableMultiLogin {
+		_ = s.RevokeAllSessions(ctx, user.ID)
+	}
+
+	return s.issueTokensForUserID(ctx, user.ID, userAgent, ip)
+}
+
+// EnrollMFA generates a new TOTP secret and recovery codes for userID,
+// storing them without enabling MFA until VerifyMFA confirms the user has
+// added the secret to an authenticator app.
+func (s *AuthService) EnrollMFA(ctx context.Context, userID uint64, ip, userAgent, requestID string) (*models.MFAEnrollResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := totp.GenerateRecoveryCodes(s.mfaConfig.RecoveryCodeCount, recoveryCodeLength)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(code), s.bcryptCost)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = string(hashed)
+	}
+
+	hashesJSON, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetPendingMFA(ctx, userID, secret, string(hashesJSON)); err != nil {
+		return nil, err
+	}
+
+	otpauthURL := totp.OTPAuthURL(s.mfaConfig.Issuer, user.Email, secret)
+
+	qrPNG, err := qrcode.Encode(otpauthURL, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &userID,
+		EventType: models.AuditEventMFAEnroll,
+		Actor:     user.Email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+	})
+
+	return &models.MFAEnrollResponse{
+		Secret:          secret,
+		OTPAuthURL:      otpauthURL,
+		QRCodePNGBase64: base64.StdEncoding.EncodeToString(qrPNG),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// VerifyMFA activates MFA for userID once it's confirmed a live TOTP code
+// from the secret EnrollMFA issued, so enrollment can't complete without
+// proving the authenticator app is actually set up correctly.
+func (s *AuthService) VerifyMFA(ctx context.Context, userID uint64, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPSecret.Valid {
+		return ErrMFANotPending
+	}
+
+	ok, err := totp.Verify(user.TOTPSecret.String, code, time.Now())
+	if err != nil || !ok {
+		return ErrInvalidMFACode
+	}
+
+	return s.userRepo.ActivateMFA(ctx, userID)
+}
+
+// DisableMFA turns MFA off after re-verifying the account password, so a
+// stolen access token alone can't be used to strip MFA protection off an
+// account.
+func (s *AuthService) DisableMFA(ctx context.Context, userID uint64, password string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	return s.userRepo.DisableMFA(ctx, userID)
+}
+
+// consumeMFACode accepts either a live TOTP code or a single-use recovery
+// code, removing the recovery code from the user's list once it's spent.
+func (s *AuthService) consumeMFACode(ctx context.Context, user *models.User, code string) bool {
+	if user.TOTPSecret.Valid {
+		if ok, err := totp.Verify(user.TOTPSecret.String, code, time.Now()); err == nil && ok {
+			return true
+		}
+	}
+
+	return s.consumeRecoveryCode(ctx, user, code)
+}
+
+// consumeRecoveryCode checks code against user's remaining recovery code
+// hashes and, on a match, persists the list with that one removed so it
+// can't be reused. The lookup-and-remove happens inside a transaction that
+// locks the user's row, so two concurrent requests racing on the same
+// recovery code can't both succeed.
+func (s *AuthService) consumeRecoveryCode(ctx context.Context, user *models.User, code string) bool {
+	consumed, err := s.userRepo.ConsumeRecoveryCode(ctx, user.ID, func(hashesJSON string) (string, bool) {
+		var hashes []string
+		if hashesJSON == "" {
+			return "", false
+		}
+		if err := json.Unmarshal([]byte(hashesJSON), &hashes); err != nil {
+			return "", false
+		}
+
+		for i, hash := range hashes {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil {
+				remaining := append(hashes[:i:i], hashes[i+1:]...)
+				remainingJSON, err := json.Marshal(remaining)
+				if err != nil {
+					return "", false
+				}
+				return string(remainingJSON), true
+			}
+		}
+		return "", false
+	})
+	if err != nil {
+		return false
+	}
+	return consumed
+}
+
+// issueRefreshToken mints a new opaque refresh token, persists its hash, and
+// returns the plaintext secret to hand back to the client. parentID links a
+// rotated token to the one it replaced.
+func (s *AuthService) issueRefreshToken(ctx context.Context, userID uint64, parentID *string, userAgent, ip string) (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	plaintext := hex.EncodeToString(secretBytes)
+
+	hash := sha256.Sum256([]byte(plaintext))
+	hashHex := hex.EncodeToString(hash[:])
+
+	id := uuid.New().String()
+	expiresAt := time.Now().Add(s.jwtConfig.RefreshExpiresIn)
+
+	if _, err := s.refreshTokenRepo.Create(ctx, id, userID, hashHex, parentID, expiresAt, userAgent, ip); err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+// CompleteOAuthCallback resolves an OIDC/social login callback to a local user,
+// either by finding an existing link, linking the provider to the currently
+// authenticated user (currentUserID != nil), or provisioning a new user.
+// It returns tokens exactly like Login/RefreshToken.
+func (s *AuthService) CompleteOAuthCallback(
+	ctx context.Context,
+	loginType, providerUserID, email, accessToken, refreshToken string,
+	expiry *time.Time,
+	currentUserID *uint64,
+	userAgent, ip string,
+) (*models.LoginResponse, error) {
+	// An existing link always wins - the provider account is already tied to a user.
+	link, err := s.userLinkRepo.FindByProvider(ctx, loginType, providerUserID)
+	if err == nil {
+		if updateErr := s.userLinkRepo.UpdateTokens(ctx, link.ID, accessToken, refreshToken, expiry); updateErr != nil {
+			return nil, updateErr
+		}
+		return s.issueTokensForUserID(ctx, link.UserID, userAgent, ip)
+	}
+	if !errors.Is(err, repository.ErrUserLinkNotFound) {
+		return nil, err
+	}
+
+	// Linking to the currently authenticated user (explicit "connect provider" flow).
+	if currentUserID != nil {
+		if _, linkErr := s.userLinkRepo.Create(ctx, *currentUserID, loginType, providerUserID, accessToken, refreshToken, expiry); linkErr != nil {
+			return nil, linkErr
+		}
+		return s.issueTokensForUserID(ctx, *currentUserID, userAgent, ip)
+	}
+
+	// No link yet - find-or-provision by email. An empty email can't be
+	// trusted as a join key: every provider account the caller never
+	// verified an address for would otherwise match the same blank-email
+	// row and get silently linked into one account.
+	if email == "" {
+		return nil, ErrOAuthEmailMissing
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			return nil, err
+		}
+		user, err = s.userRepo.CreateWithLoginType(ctx, email, loginType)
+		if err != nil {
+			return nil, err
+		}
+	} else if s.oidcConfig.RequireExplicitLink && user.LoginType == models.LoginTypePassword {
+		// A password account cannot be silently converted to OIDC.
+		return nil, ErrLoginMethodMismatch
+	}
+
+	if _, err := s.userLinkRepo.Create(ctx, user.ID, loginType, providerUserID, accessToken, refreshToken, expiry); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokensForUserID(ctx, user.ID, userAgent, ip)
+}
+
+// issueTokensForUserID loads a user and mints a fresh access/refresh token pair
+func (s *AuthService) issueTokensForUserID(ctx context.Context, userID uint64, userAgent, ip string) (*models.LoginResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserNotActive
+	}
+
+	accessToken, err := s.generateAccessToken(user)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate new refresh token
-	newRefreshToken, err := jwt.GenerateRefreshToken(user.ID, s.jwtConfig.Secret, s.jwtConfig.RefreshExpiresIn)
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID, nil, userAgent, ip)
 	if err != nil {
 		return nil, err
 	}
 
+	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
+
 	return &models.LoginResponse{
 		User:         user.ToResponse(),
 		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
+		RefreshToken: refreshToken,
 		ExpiresIn:    int64(s.jwtConfig.ExpiresIn.Seconds()),
 	}, nil
 }
 
+// RefreshToken refreshes an access token using a refresh token
+func (s *AuthService) RefreshToken(ctx context.Context, presentedToken, userAgent, ip, requestID string) (*models.LoginResponse, error) {
+	hash := sha256.Sum256([]byte(presentedToken))
+	hashHex := hex.EncodeToString(hash[:])
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, err
+	}
+	newPlaintext := hex.EncodeToString(secretBytes)
+	newHash := sha256.Sum256([]byte(newPlaintext))
+	newHashHex := hex.EncodeToString(newHash[:])
+	newID := uuid.New().String()
+	newExpiresAt := time.Now().Add(s.jwtConfig.RefreshExpiresIn)
+
+	// Rotate locks the presented token's row for the rest of this
+	// transaction, so a concurrent request racing to refresh the same token
+	// can't also pass the revoked check before this one commits.
+	stored, err := s.refreshTokenRepo.Rotate(ctx, hashHex, newID, newHashHex, newExpiresAt, userAgent, ip)
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenReused) {
+			// This token was already rotated (or revoked) once before - presenting it
+			// again means it was copied by an attacker. Burn the whole session family,
+			// including any access tokens already handed out.
+			_ = s.RevokeAllSessions(ctx, stored.UserID)
+			return nil, ErrInvalidToken
+		}
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserNotActive
+	}
+
+	accessToken, err := s.generateAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &user.ID,
+		EventType: models.AuditEventTokenRefresh,
+		Actor:     user.Email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+	})
+
+	return &models.LoginResponse{
+		User:         user.ToResponse(),
+		AccessToken:  accessToken,
+		RefreshToken: newPlaintext,
+		ExpiresIn:    int64(s.jwtConfig.ExpiresIn.Seconds()),
+	}, nil
+}
+
+// RevokeAllSessions revokes every refresh token for a user and invalidates
+// any access token already issued to them, logging them out of every
+// device immediately rather than once their current access tokens expire.
+// Used by admin logout-everywhere and single-session mode.
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID uint64) error {
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if s.tokenValidator != nil {
+		return s.tokenValidator.RevokeAllForUser(ctx, userID)
+	}
+	return nil
+}
+
+// ListSessions returns a user's active (non-revoked, non-expired) sessions
+// with their device metadata, for GET /auth/sessions.
+func (s *AuthService) ListSessions(ctx context.Context, userID uint64) ([]*models.SessionResponse, error) {
+	sessions, err := s.refreshTokenRepo.FindActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = session.ToResponse()
+	}
+
+	return responses, nil
+}
+
+// RevokeToken invalidates a single access token immediately instead of
+// waiting for it to expire naturally. Used by Logout.
+func (s *AuthService) RevokeToken(ctx context.Context, accessToken string) error {
+	if s.tokenValidator == nil {
+		return nil
+	}
+
+	claims, err := s.parseAccessToken(accessToken)
+	if err != nil {
+		// Already invalid or expired - nothing left to revoke.
+		return nil
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return s.tokenValidator.RevokeJTI(ctx, claims.ID, ttl)
+}
+
+// RevokeSession revokes the refresh-token session tied to refreshToken, so a
+// client that calls Logout can't still mint a fresh access token via
+// RefreshToken afterward. Unlike RevokeAllSessions, this only ends the
+// session presented here, not every device the user is logged in on.
+func (s *AuthService) RevokeSession(ctx context.Context, refreshToken string) error {
+	hash := sha256.Sum256([]byte(refreshToken))
+	hashHex := hex.EncodeToString(hash[:])
+
+	stored, err := s.refreshTokenRepo.FindByTokenHash(ctx, hashHex)
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			// Already invalid or never existed - nothing left to revoke.
+			return nil
+		}
+		return err
+	}
+
+	return s.refreshTokenRepo.Revoke(ctx, stored.ID)
+}
+
+// IsRevoked reports whether an access token has been individually revoked
+// or predates its user's last logout-everywhere. Returns false when Redis
+// isn't configured, since revocation can't be enforced without it.
+func (s *AuthService) IsRevoked(ctx context.Context, claims *jwt.AccessClaims) (bool, error) {
+	if s.tokenValidator == nil {
+		return false, nil
+	}
+	return s.tokenValidator.IsRevoked(ctx, claims)
+}
+
 // GetUserByID gets a user by ID
 func (s *AuthService) GetUserByID(ctx context.Context, userID uint64) (*models.User, error) {
 	return s.userRepo.FindByID(ctx, userID)
@@ -165,8 +711,249 @@ func (s *AuthService) UpdateUser(ctx context.Context, userID uint64, req *models
 	return s.userRepo.FindByID(ctx, userID)
 }
 
+// SearchUsers returns a filtered, paginated list of users for GET /admin/users.
+func (s *AuthService) SearchUsers(ctx context.Context, filter models.UserFilter) ([]*models.User, int64, error) {
+	return s.userRepo.Search(ctx, filter)
+}
+
+// AdminUpdateUser changes a user's role and/or active status on an admin's
+// behalf and records who did it.
+func (s *AuthService) AdminUpdateUser(ctx context.Context, targetUserID uint64, req *models.AdminUpdateUserRequest, adminUserID uint64, ip, userAgent, requestID string) (*models.User, error) {
+	if req.Role != "" {
+		if err := s.userRepo.UpdateRole(ctx, targetUserID, req.Role); err != nil {
+			return nil, err
+		}
+	}
+	if req.IsActive != nil {
+		if err := s.userRepo.SetActive(ctx, targetUserID, *req.IsActive); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepo.FindByID(ctx, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &adminUserID,
+		EventType: models.AuditEventAdminUserUpdate,
+		Actor:     user.Email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+		Metadata:  map[string]interface{}{"target_user_id": targetUserID, "role": req.Role, "is_active": req.IsActive},
+	})
+
+	return user, nil
+}
+
+// AdminDeleteUser soft-deletes a user (see UserRepository.Delete) and
+// records who did it.
+func (s *AuthService) AdminDeleteUser(ctx context.Context, targetUserID, adminUserID uint64, ip, userAgent, requestID string) error {
+	if err := s.userRepo.Delete(ctx, targetUserID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &adminUserID,
+		EventType: models.AuditEventAdminUserDelete,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+		Metadata:  map[string]interface{}{"target_user_id": targetUserID},
+	})
+
+	return nil
+}
+
+// AdminResetPassword issues a one-time password reset token for targetUserID,
+// for an admin to relay to the user out-of-band. Only the token's hash is
+// persisted; the plaintext is returned exactly once.
+func (s *AuthService) AdminResetPassword(ctx context.Context, targetUserID, adminUserID uint64, ip, userAgent, requestID string) (*models.AdminResetPasswordResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenHash, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+	expiresAt := time.Now().Add(passwordResetTTL)
+	if err := s.passwordResetRepo.Create(ctx, id, targetUserID, tokenHash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &adminUserID,
+		EventType: models.AuditEventAdminPasswordReset,
+		Actor:     user.Email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+		Metadata:  map[string]interface{}{"target_user_id": targetUserID},
+	})
+
+	return &models.AdminResetPasswordResponse{
+		ResetToken: token,
+		ExpiresAt:  expiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// generateOpaqueToken returns a random 32-byte URL-safe token and the hex
+// SHA-256 hash of it, following RefreshToken/PasswordResetToken's pattern
+// of persisting only the hash of an opaque secret handed to the caller.
+func generateOpaqueToken() (token, tokenHash string, err error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(secretBytes)
+	sum := sha256.Sum256([]byte(token))
+	return token, hex.EncodeToString(sum[:]), nil
+}
+
+// RequestEmailVerification issues a one-time verification token for userID
+// and emails it, if SMTP is configured. Already-verified accounts get a
+// no-op success rather than an error, so a stale "resend" button can't leak
+// whether verification already happened.
+func (s *AuthService) RequestEmailVerification(ctx context.Context, userID uint64) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt.Valid {
+		return nil
+	}
+
+	token, tokenHash, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	id := uuid.NewString()
+	expiresAt := time.Now().Add(s.emailConfig.VerificationTTL)
+	if err := s.emailVerificationRepo.Create(ctx, id, userID, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	if s.mailer != nil {
+		link := fmt.Sprintf("%s?token=%s", s.emailConfig.VerificationURLBase, token)
+		_ = s.mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "Verify your email address",
+			Body:    fmt.Sprintf("Confirm your email by visiting: %s\n\nThis link expires at %s.", link, expiresAt.Format(time.RFC3339)),
+		})
+	}
+
+	return nil
+}
+
+// VerifyEmail consumes a verification token minted by
+// RequestEmailVerification and marks the owning account's email verified.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	hash := sha256.Sum256([]byte(token))
+	verification, err := s.emailVerificationRepo.Consume(ctx, hex.EncodeToString(hash[:]))
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailVerificationTokenNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	if err := s.userRepo.SetEmailVerified(ctx, verification.UserID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &verification.UserID,
+		EventType: models.AuditEventEmailVerified,
+	})
+
+	return nil
+}
+
+// ForgotPassword issues a password-reset token for the account with the
+// given email, if one exists, and emails it. The caller always sees success
+// regardless of whether the email matched an account, so this endpoint
+// can't be used to enumerate registered users.
+func (s *AuthService) ForgotPassword(ctx context.Context, email, ip, userAgent, requestID string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, tokenHash, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	id := uuid.NewString()
+	expiresAt := time.Now().Add(s.emailConfig.PasswordResetTTL)
+	if err := s.passwordResetRepo.Create(ctx, id, user.ID, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	if s.mailer != nil {
+		link := fmt.Sprintf("%s?token=%s", s.emailConfig.PasswordResetURLBase, token)
+		_ = s.mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body:    fmt.Sprintf("Reset your password by visiting: %s\n\nThis link expires at %s. If you didn't request this, you can ignore this email.", link, expiresAt.Format(time.RFC3339)),
+		})
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &user.ID,
+		EventType: models.AuditEventPasswordResetRequested,
+		Actor:     user.Email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+	})
+
+	return nil
+}
+
+// ResetPassword consumes a password-reset token minted by ForgotPassword (or
+// AdminResetPassword) and sets a new password for its owning account.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword, ip, userAgent, requestID string) error {
+	hash := sha256.Sum256([]byte(token))
+	reset, err := s.passwordResetRepo.Consume(ctx, hex.EncodeToString(hash[:]))
+	if err != nil {
+		if errors.Is(err, repository.ErrPasswordResetTokenNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), s.bcryptCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, reset.UserID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &reset.UserID,
+		EventType: models.AuditEventPasswordResetCompleted,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+	})
+
+	return nil
+}
+
 // ChangePassword changes a user's password
-func (s *AuthService) ChangePassword(ctx context.Context, userID uint64, req *models.ChangePasswordRequest) error {
+func (s *AuthService) ChangePassword(ctx context.Context, userID uint64, req *models.ChangePasswordRequest, ip, userAgent, requestID string) error {
 	// Get user
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
@@ -185,11 +972,24 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uint64, req *mo
 	}
 
 	// Update password
-	return s.userRepo.UpdatePassword(ctx, userID, string(hashedPassword))
+	if err := s.userRepo.UpdatePassword(ctx, userID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &userID,
+		EventType: models.AuditEventPasswordChange,
+		Actor:     user.Email,
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+	})
+
+	return nil
 }
 
 // CreateAPIKey creates a new API key for a user
-func (s *AuthService) CreateAPIKey(ctx context.Context, userID uint64, req *models.CreateAPIKeyRequest) (*models.APIKeyWithSecret, error) {
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID uint64, req *models.CreateAPIKeyRequest, ip, userAgent, requestID string) (*models.APIKeyWithSecret, error) {
 	// Check if user has reached max API keys
 	count, err := s.apiKeyRepo.CountByUserID(ctx, userID)
 	if err != nil {
@@ -200,6 +1000,12 @@ func (s *AuthService) CreateAPIKey(ctx context.Context, userID uint64, req *mode
 		return nil, ErrMaxAPIKeysReached
 	}
 
+	for _, scope := range req.Scopes {
+		if !scopes.Valid(scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+
 	// Generate random API key
 	keyBytes := make([]byte, 32)
 	if _, err := rand.Read(keyBytes); err != nil {
@@ -207,7 +1013,7 @@ func (s *AuthService) CreateAPIKey(ctx context.Context, userID uint64, req *mode
 	}
 
 	fullKey := "cp_" + hex.EncodeToString(keyBytes) // cp_ prefix for captcha-platform
-	keyPrefix := fullKey[:11]                        // First 11 chars including prefix
+	keyPrefix := fullKey[:11]                       // First 11 chars including prefix
 
 	// Hash the key for storage
 	keyHash := sha256.Sum256([]byte(fullKey))
@@ -229,15 +1035,11 @@ func (s *AuthService) CreateAPIKey(ctx context.Context, userID uint64, req *mode
 	// Convert scopes to JSON string
 	scopesJSON := "[]"
 	if len(req.Scopes) > 0 {
-		// Simple JSON array construction
-		scopesJSON = "["
-		for i, scope := range req.Scopes {
-			if i > 0 {
-				scopesJSON += ","
-			}
-			scopesJSON += `"` + scope + `"`
+		encoded, err := json.Marshal(req.Scopes)
+		if err != nil {
+			return nil, err
 		}
-		scopesJSON += "]"
+		scopesJSON = string(encoded)
 	}
 
 	// Create API key
@@ -246,6 +1048,16 @@ func (s *AuthService) CreateAPIKey(ctx context.Context, userID uint64, req *mode
 		return nil, err
 	}
 
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &userID,
+		EventType: models.AuditEventAPIKeyCreated,
+		Actor:     fmt.Sprintf("user:%d", userID),
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+		Metadata:  map[string]interface{}{"key_id": apiKey.ID, "name": apiKey.Name},
+	})
+
 	// Return with full key (only time it's shown)
 	return &models.APIKeyWithSecret{
 		APIKeyResponse: *apiKey.ToResponse(),
@@ -269,8 +1081,22 @@ func (s *AuthService) ListAPIKeys(ctx context.Context, userID uint64) ([]*models
 }
 
 // DeleteAPIKey deletes an API key
-func (s *AuthService) DeleteAPIKey(ctx context.Context, userID, keyID uint64) error {
-	return s.apiKeyRepo.Delete(ctx, keyID, userID)
+func (s *AuthService) DeleteAPIKey(ctx context.Context, userID, keyID uint64, ip, userAgent, requestID string) error {
+	if err := s.apiKeyRepo.Delete(ctx, keyID, userID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, AuditEventInput{
+		UserID:    &userID,
+		EventType: models.AuditEventAPIKeyDeleted,
+		Actor:     fmt.Sprintf("user:%d", userID),
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: requestID,
+		Metadata:  map[string]interface{}{"key_id": keyID},
+	})
+
+	return nil
 }
 
 // ValidateAPIKey validates an API key and returns the associated user
@@ -301,8 +1127,74 @@ func (s *AuthService) ValidateAPIKey(ctx context.Context, key string) (*models.U
 		return nil, nil, ErrUserNotActive
 	}
 
-	// Increment usage
-	_ = s.apiKeyRepo.IncrementUsage(ctx, apiKey.ID)
+	// Usage (total_requests/last_used_at) is recorded by the gateway's
+	// batched reporter via RecordAPIKeyUsage, not here, so a validation
+	// doesn't cost a write on every request.
 
 	return user, apiKey, nil
-}
\ No newline at end of file
+}
+
+// RotateAPIKey issues a new secret for an existing key while preserving its
+// ID, name, and scopes. The previous secret keeps validating for the
+// configured grace period so in-flight callers have time to pick up the
+// new one.
+func (s *AuthService) RotateAPIKey(ctx context.Context, userID, keyID uint64) (*models.APIKeyWithSecret, error) {
+	apiKey, err := s.apiKeyRepo.FindByID(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey.UserID != userID {
+		return nil, ErrAPIKeyNotOwned
+	}
+
+	keyBytes := make([]byte, 32)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, err
+	}
+
+	fullKey := "cp_" + hex.EncodeToString(keyBytes)
+	newKeyHash := sha256.Sum256([]byte(fullKey))
+	newKeyHashHex := hex.EncodeToString(newKeyHash[:])
+
+	oldKeyExpiresAt := time.Now().Add(s.apiKeyConfig.RotationGracePeriod)
+	if err := s.apiKeyRepo.Rotate(ctx, keyID, newKeyHashHex, apiKey.KeyHash, oldKeyExpiresAt); err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.apiKeyRepo.FindByID(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyWithSecret{
+		APIKeyResponse: *rotated.ToResponse(),
+		Key:            fullKey,
+	}, nil
+}
+
+// GetAPIKeyUsage returns hourly request-count buckets for an API key owned
+// by userID, covering the last lookback window.
+func (s *AuthService) GetAPIKeyUsage(ctx context.Context, userID, keyID uint64, lookback time.Duration) (*models.APIKeyUsageResponse, error) {
+	apiKey, err := s.apiKeyRepo.FindByID(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey.UserID != userID {
+		return nil, ErrAPIKeyNotOwned
+	}
+
+	buckets, err := s.apiKeyRepo.FindUsageBuckets(ctx, keyID, time.Now().Add(-lookback))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyUsageResponse{KeyID: keyID, Buckets: buckets}, nil
+}
+
+// RecordAPIKeyUsage applies a batch of usage increments reported by the
+// gateway.
+func (s *AuthService) RecordAPIKeyUsage(ctx context.Context, increments []models.APIKeyUsageIncrement) error {
+	return s.apiKeyRepo.RecordUsageBatch(ctx, increments)
+}
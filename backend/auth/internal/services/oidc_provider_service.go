@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/captcha-platform/auth/internal/config"
+	"github.com/captcha-platform/auth/internal/models"
+	"github.com/captcha-platform/auth/internal/repository"
+	"github.com/captcha-platform/auth/pkg/jwt"
+)
+
+var (
+	ErrInvalidRedirectURI    = errors.New("redirect_uri is not registered for this client")
+	ErrPKCERequired          = errors.New("code_challenge is required")
+	ErrUnsupportedPKCEMethod = errors.New("only the S256 code_challenge_method is supported")
+	ErrInvalidCodeVerifier   = errors.New("code_verifier does not match the original code_challenge")
+	ErrOIDCNotConfigured     = errors.New("this service is not configured as an OIDC provider (RS256 signing is required)")
+	ErrUnauthorizedClient    = errors.New("client is not authorized to use this grant type")
+	ErrInvalidClientSecret   = errors.New("client_secret is invalid or missing for this confidential client")
+	ErrInvalidRefreshToken   = errors.New("refresh_token is invalid, expired, or already used")
+)
+
+// OIDCProviderService implements this service's own OIDC provider (OP)
+// endpoints: an RS256-backed, PKCE-required authorization code flow that
+// lets other services federate sign-in through it, as distinct from
+// AuthService's OAuth handling, which consumes *external* OIDC providers
+// for social login.
+type OIDCProviderService struct {
+	oidcRepo   *repository.OIDCRepository
+	userRepo   *repository.UserRepository
+	keyManager *jwt.KeyManager
+	opConfig   config.OPConfig
+	bcryptCost int
+}
+
+// NewOIDCProviderService creates a new OIDCProviderService. keyManager may
+// be nil when the service is configured for HS256, in which case Authorize,
+// Token, RefreshToken and ClientCredentials return ErrOIDCNotConfigured and
+// Introspect reports every token inactive; RegisterClient and UserInfo
+// work either way.
+func NewOIDCProviderService(oidcRepo *repository.OIDCRepository, userRepo *repository.UserRepository, keyManager *jwt.KeyManager, opConfig config.OPConfig, bcryptCost int) *OIDCProviderService {
+	return &OIDCProviderService{
+		oidcRepo:   oidcRepo,
+		userRepo:   userRepo,
+		keyManager: keyManager,
+		opConfig:   opConfig,
+		bcryptCost: bcryptCost,
+	}
+}
+
+// Authorize validates an authorization request and issues a single-use
+// authorization code bound to userID, the requesting client, its redirect
+// URI, and the PKCE challenge it must later prove possession of.
+func (s *OIDCProviderService) Authorize(ctx context.Context, clientID, redirectURI, codeChallenge, codeChallengeMethod, nonce string, userID uint64) (string, error) {
+	if s.keyManager == nil {
+		return "", ErrOIDCNotConfigured
+	}
+
+	client, err := s.oidcRepo.FindClientByClientID(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+
+	if !containsString(client.AllowedGrantTypes, "authorization_code") {
+		return "", ErrUnauthorizedClient
+	}
+
+	if !containsString(client.RedirectURIs, redirectURI) {
+		return "", ErrInvalidRedirectURI
+	}
+
+	if codeChallenge == "" {
+		return "", ErrPKCERequired
+	}
+	if codeChallengeMethod != "S256" {
+		return "", ErrUnsupportedPKCEMethod
+	}
+
+	code, err := randomURLSafeToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	authCode := &models.OIDCAuthorizationCode{
+		Code:                code,
+		ClientID:            clientID,
+		UserID:              userID,
+		RedirectURI:         redirectURI,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		Nonce:               nonce,
+		ExpiresAt:           time.Now().Add(s.opConfig.AuthCodeTTL),
+	}
+
+	if err := s.oidcRepo.CreateAuthorizationCode(ctx, authCode); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Token exchanges an authorization code and its PKCE verifier for an ID
+// token, an access token, a refresh token, and the associated token metadata.
+func (s *OIDCProviderService) Token(ctx context.Context, clientID, clientSecret, redirectURI, code, codeVerifier string) (*models.OIDCTokenResponse, error) {
+	if s.keyManager == nil {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	client, err := s.oidcRepo.FindClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyClientSecret(client, clientSecret); err != nil {
+		return nil, err
+	}
+
+	authCode, err := s.oidcRepo.ConsumeAuthorizationCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if authCode.ClientID != clientID || authCode.RedirectURI != redirectURI {
+		return nil, ErrInvalidRedirectURI
+	}
+
+	if !verifyPKCE(authCode.CodeChallenge, codeVerifier) {
+		return nil, ErrInvalidCodeVerifier
+	}
+
+	user, err := s.userRepo.FindByID(ctx, authCode.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := jwt.GenerateIDToken(s.keyManager, user.ID, user.Email, s.opConfig.Issuer, clientID, authCode.Nonce, s.opConfig.IDTokenExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := jwt.GenerateAccessTokenRS(s.keyManager, user.ID, user.Email, user.Role, s.opConfig.Issuer, s.opConfig.IDTokenExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, clientID, user.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCTokenResponse{
+		AccessToken:  accessToken,
+		IDToken:      idToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.opConfig.IDTokenExpiresIn.Seconds()),
+	}, nil
+}
+
+// RefreshToken exchanges a refresh token issued by Token for a new access
+// token and ID token, rotating the refresh token in the same way the
+// service's own login sessions do (see AuthService.RefreshToken) - the
+// presented token is immediately revoked and a new one takes its place, so
+// a stolen, already-rotated token can be detected by its reuse.
+func (s *OIDCProviderService) RefreshToken(ctx context.Context, clientID, clientSecret, presentedToken string) (*models.OIDCTokenResponse, error) {
+	if s.keyManager == nil {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	client, err := s.oidcRepo.FindClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyClientSecret(client, clientSecret); err != nil {
+		return nil, err
+	}
+	if !containsString(client.AllowedGrantTypes, "refresh_token") {
+		return nil, ErrUnauthorizedClient
+	}
+
+	hash := sha256.Sum256([]byte(presentedToken))
+	hashHex := hex.EncodeToString(hash[:])
+
+	stored, err := s.oidcRepo.FindRefreshTokenByHash(ctx, hashHex)
+	if err != nil {
+		if errors.Is(err, repository.ErrOIDCRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.ClientID != clientID || stored.RevokedAt.Valid || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.oidcRepo.RevokeRefreshToken(ctx, stored.ID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := jwt.GenerateIDToken(s.keyManager, user.ID, user.Email, s.opConfig.Issuer, clientID, "", s.opConfig.IDTokenExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := jwt.GenerateAccessTokenRS(s.keyManager, user.ID, user.Email, user.Role, s.opConfig.Issuer, s.opConfig.IDTokenExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := stored.ID
+	newRefreshToken, err := s.issueRefreshToken(ctx, clientID, user.ID, &parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCTokenResponse{
+		AccessToken:  accessToken,
+		IDToken:      idToken,
+		RefreshToken: newRefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.opConfig.IDTokenExpiresIn.Seconds()),
+	}, nil
+}
+
+// ClientCredentials issues an access token to a confidential client acting
+// on its own behalf, with no end user involved - there's no ID token or
+// refresh token, since there's no subject to authenticate or session to
+// keep alive.
+func (s *OIDCProviderService) ClientCredentials(ctx context.Context, clientID, clientSecret string) (*models.OIDCTokenResponse, error) {
+	if s.keyManager == nil {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	client, err := s.oidcRepo.FindClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.ClientSecretHash == "" {
+		// client_credentials authenticates the client itself, so a public
+		// client (no secret) can never use it.
+		return nil, ErrInvalidClientSecret
+	}
+	if err := s.verifyClientSecret(client, clientSecret); err != nil {
+		return nil, err
+	}
+	if !containsString(client.AllowedGrantTypes, "client_credentials") {
+		return nil, ErrUnauthorizedClient
+	}
+
+	accessToken, err := jwt.GenerateAccessTokenRS(s.keyManager, client.ID, "", "service", s.opConfig.Issuer, s.opConfig.IDTokenExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.opConfig.IDTokenExpiresIn.Seconds()),
+	}, nil
+}
+
+// RegisterClient registers a new relying party. confidential clients
+// receive a generated client_secret (returned once, in plaintext); public
+// clients must authenticate with PKCE alone.
+func (s *OIDCProviderService) RegisterClient(ctx context.Context, name string, redirectURIs, allowedGrantTypes, allowedScopes []string, confidential bool) (*models.OIDCClient, string, error) {
+	clientID, err := randomURLSafeToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var plaintextSecret, secretHash string
+	if confidential {
+		plaintextSecret, err = randomURLSafeToken(32)
+		if err != nil {
+			return nil, "", err
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(plaintextSecret), s.bcryptCost)
+		if err != nil {
+			return nil, "", err
+		}
+		secretHash = string(hashed)
+	}
+
+	client := &models.OIDCClient{
+		ClientID:          clientID,
+		ClientSecretHash:  secretHash,
+		Name:              name,
+		RedirectURIs:      redirectURIs,
+		AllowedGrantTypes: allowedGrantTypes,
+		AllowedScopes:     allowedScopes,
+	}
+
+	if err := s.oidcRepo.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	return client, plaintextSecret, nil
+}
+
+// verifyClientSecret checks clientSecret against client's stored hash.
+// Public clients (no stored hash) skip this check entirely - PKCE is their
+// proof of possession.
+func (s *OIDCProviderService) verifyClientSecret(client *models.OIDCClient, clientSecret string) error {
+	if client.ClientSecretHash == "" {
+		return nil
+	}
+	if clientSecret == "" {
+		return ErrInvalidClientSecret
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(client.ClientSecretHash), []byte(clientSecret)); err != nil {
+		return ErrInvalidClientSecret
+	}
+	return nil
+}
+
+// issueRefreshToken generates and persists a new OIDC refresh token for
+// clientID/userID, returning the plaintext secret (only the hash is
+// stored). parentID links it to the token it was rotated from, if any.
+func (s *OIDCProviderService) issueRefreshToken(ctx context.Context, clientID string, userID uint64, parentID *string) (string, error) {
+	plaintext, err := randomURLSafeToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(plaintext))
+	hashHex := hex.EncodeToString(hash[:])
+
+	id := uuid.New().String()
+	expiresAt := time.Now().Add(s.opConfig.RefreshTokenTTL)
+
+	if err := s.oidcRepo.CreateRefreshToken(ctx, id, clientID, userID, hashHex, parentID, expiresAt); err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+// UserInfo returns the OIDC userinfo claims for an already-authenticated
+// subject (the caller has validated the bearer access token upstream).
+func (s *OIDCProviderService) UserInfo(ctx context.Context, userID uint64) (*models.OIDCUserInfoResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCUserInfoResponse{
+		Subject: fmt.Sprintf("%d", user.ID),
+		Email:   user.Email,
+	}, nil
+}
+
+// Introspect reports whether an access token is currently valid, per RFC
+// 7662. Meant to be called only by trusted relying parties.
+func (s *OIDCProviderService) Introspect(token string) *models.OIDCIntrospectResponse {
+	if s.keyManager == nil {
+		return &models.OIDCIntrospectResponse{Active: false}
+	}
+
+	claims, err := jwt.ValidateAccessTokenRS(token, s.keyManager)
+	if err != nil {
+		return &models.OIDCIntrospectResponse{Active: false}
+	}
+
+	return &models.OIDCIntrospectResponse{
+		Active:   true,
+		Subject:  fmt.Sprintf("%d", claims.UserID),
+		Email:    claims.Email,
+		ExpireAt: claims.ExpiresAt.Unix(),
+	}
+}
+
+// verifyPKCE checks a code_verifier against the code_challenge recorded at
+// authorize time, per RFC 7636's S256 transform.
+func verifyPKCE(codeChallenge, codeVerifier string) bool {
+	if codeVerifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(codeVerifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(codeChallenge)) == 1
+}
+
+func randomURLSafeToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
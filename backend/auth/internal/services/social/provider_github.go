@@ -0,0 +1,186 @@
+package social
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/captcha-platform/auth/internal/config"
+)
+
+// GitHub predates OIDC and publishes no discovery document, so its
+// endpoints are fixed here rather than fetched like oidcProvider's.
+const (
+	githubAuthorizeEndpoint  = "https://github.com/login/oauth/authorize"
+	githubTokenEndpoint      = "https://github.com/login/oauth/access_token"
+	githubUserEndpoint       = "https://api.github.com/user"
+	githubUserEmailsEndpoint = "https://api.github.com/user/emails"
+)
+
+// githubProvider implements IdentityProvider for GitHub's OAuth2 (not OIDC)
+// authorization-code flow.
+type githubProvider struct {
+	cfg config.OIDCProviderConfig
+}
+
+func newGitHubProvider(cfg config.OIDCProviderConfig) *githubProvider {
+	return &githubProvider{cfg: cfg}
+}
+
+func (p *githubProvider) Authorize(state string) string {
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"read:user", "user:email"}
+	}
+
+	params := url.Values{
+		"client_id":    {p.cfg.ClientID},
+		"redirect_uri": {p.cfg.RedirectURL},
+		"scope":        {strings.Join(scopes, " ")},
+		"state":        {state},
+	}
+	return githubAuthorizeEndpoint + "?" + params.Encode()
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code string) (UserInfo, error) {
+	form := url.Values{
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubTokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return UserInfo{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json") // GitHub defaults to form-encoded without this
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return UserInfo{}, fmt.Errorf("social: github token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return UserInfo{}, fmt.Errorf("social: github token exchange failed: status %d", resp.StatusCode)
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return UserInfo{}, err
+	}
+	if tok.Error != "" {
+		return UserInfo{}, fmt.Errorf("social: github token exchange failed: %s", tok.Error)
+	}
+
+	return p.fetchUser(ctx, tok.AccessToken)
+}
+
+// fetchUser retrieves the authenticated user's GitHub profile. GitHub's
+// /user endpoint only populates Email when the account's primary email is
+// public; when it comes back blank, fetchVerifiedEmail falls back to
+// /user/emails (which the user:email scope also grants access to) so a
+// private-email account still resolves to a real, verified address. An
+// account with no verified address at all comes back with an empty Email,
+// which CompleteOAuthCallback rejects rather than use as a find-or-create
+// key.
+func (p *githubProvider) fetchUser(ctx context.Context, accessToken string) (UserInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubUserEndpoint, nil)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return UserInfo{}, fmt.Errorf("social: failed to fetch github user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return UserInfo{}, fmt.Errorf("social: failed to fetch github user: status %d", resp.StatusCode)
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return UserInfo{}, err
+	}
+
+	email := user.Email
+	if email == "" {
+		email, err = p.fetchVerifiedEmail(ctx, accessToken)
+		if err != nil {
+			return UserInfo{}, err
+		}
+	}
+
+	return UserInfo{
+		Subject:     strconv.FormatInt(user.ID, 10),
+		Email:       email,
+		AccessToken: accessToken,
+	}, nil
+}
+
+// fetchVerifiedEmail calls /user/emails and returns the account's primary,
+// verified address, falling back to the first verified address if none is
+// marked primary. Returns "" (never an error on its own) when the account
+// has no verified email at all, so the caller can decide how to treat that.
+func (p *githubProvider) fetchVerifiedEmail(ctx context.Context, accessToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubUserEmailsEndpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("social: failed to fetch github user emails: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("social: failed to fetch github user emails: status %d", resp.StatusCode)
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+		return "", err
+	}
+
+	var firstVerified string
+	for _, e := range emails {
+		if !e.Verified {
+			continue
+		}
+		if e.Primary {
+			return e.Email, nil
+		}
+		if firstVerified == "" {
+			firstVerified = e.Email
+		}
+	}
+
+	return firstVerified, nil
+}
@@ -0,0 +1,60 @@
+package social
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/captcha-platform/auth/internal/config"
+)
+
+// Registry holds every identity provider this service was configured with,
+// keyed by the same name used as login_type/provider in the database.
+type Registry struct {
+	providers map[string]IdentityProvider
+}
+
+// NewRegistry builds an IdentityProvider for every provider enabled in cfg
+// and fetches each generic OIDC provider's discovery document once, so
+// Authorize can build a redirect URL synchronously afterwards instead of
+// doing I/O on every login attempt. A provider whose discovery document
+// can't be fetched at startup fails the whole registry - better to find out
+// at boot than on a user's first login.
+func NewRegistry(ctx context.Context, cfg config.OIDCConfig) (*Registry, error) {
+	r := &Registry{providers: make(map[string]IdentityProvider, len(cfg.Providers))}
+
+	for name, providerCfg := range cfg.Providers {
+		var (
+			provider IdentityProvider
+			err      error
+		)
+
+		switch name {
+		case "github":
+			provider = newGitHubProvider(providerCfg)
+		default:
+			provider, err = newOIDCProvider(ctx, providerCfg)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("social: failed to register provider %q: %w", name, err)
+		}
+		r.providers[name] = provider
+	}
+
+	return r, nil
+}
+
+// Get returns the named provider, or false if it isn't configured.
+func (r *Registry) Get(name string) (IdentityProvider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names returns every configured provider's name.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	return names
+}
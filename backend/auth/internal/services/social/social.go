@@ -0,0 +1,29 @@
+// Package social lets AuthService accept logins from external identity
+// providers behind one interface, whether or not the provider actually
+// speaks OIDC - GitHub, for instance, predates OIDC and has no discovery
+// document, but still needs to plug into the same authorization-code flow
+// as a standards-compliant provider like Google.
+package social
+
+import "context"
+
+// UserInfo is the subset of an identity provider's profile AuthService needs
+// to find-or-create a local user and link the provider's account to it.
+type UserInfo struct {
+	Subject      string
+	Email        string
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+}
+
+// IdentityProvider is one external identity provider this service can log
+// users in through.
+type IdentityProvider interface {
+	// Authorize returns the URL to redirect the user to, with state embedded
+	// for the later callback to verify against.
+	Authorize(state string) string
+	// Exchange trades an authorization code from the provider's callback for
+	// the authenticated user's profile.
+	Exchange(ctx context.Context, code string) (UserInfo, error)
+}
@@ -0,0 +1,126 @@
+// Package shutdown coordinates the auth service's graceful-drain sequence:
+// fail readiness first so load balancers stop routing new traffic, wait out
+// a pre-stop delay for that to propagate, then drain the HTTP server and
+// background workers before the process exits.
+package shutdown
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/captcha-platform/auth/pkg/logger"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Readiness backs the /readyz endpoint. It starts healthy; Fail flips it
+// permanently so a load balancer stops sending new traffic once shutdown
+// begins. /livez never consults it - that endpoint only reports whether the
+// process itself is alive.
+type Readiness struct {
+	ready int32
+}
+
+// NewReadiness returns a Readiness that reports healthy until Fail is called.
+func NewReadiness() *Readiness {
+	r := &Readiness{}
+	atomic.StoreInt32(&r.ready, 1)
+	return r
+}
+
+// Fail marks the service as not ready.
+func (r *Readiness) Fail() {
+	atomic.StoreInt32(&r.ready, 0)
+}
+
+// OK reports whether the service is currently ready.
+func (r *Readiness) OK() bool {
+	return atomic.LoadInt32(&r.ready) == 1
+}
+
+// ConnTracker counts the HTTP server's open connections via
+// http.Server.ConnState, so the drain loop can report how many remain once a
+// second while it waits for them to close.
+type ConnTracker struct {
+	open int64
+}
+
+// ConnState is assigned to http.Server.ConnState.
+func (t *ConnTracker) ConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&t.open, 1)
+	case http.StateClosed, http.StateHijacked:
+		atomic.AddInt64(&t.open, -1)
+	}
+}
+
+// Count returns the current number of open connections.
+func (t *ConnTracker) Count() int64 {
+	return atomic.LoadInt64(&t.open)
+}
+
+// Closer is a named background-worker teardown step run during Drain.
+type Closer struct {
+	Name string
+	Func func() error
+}
+
+// Drain runs the full shutdown sequence: fail readiness, sleep preStopDelay,
+// stop the HTTP server from accepting new connections and wait up to
+// drainTimeout for in-flight requests to finish (logging tracker's open
+// connection count once a second while it waits), then close closers
+// concurrently via a shared errgroup - they're independent of each other but
+// must not run until the HTTP drain has released whatever they're backing.
+// A closer or HTTP drain error is returned rather than treated as fatal;
+// the process is exiting either way.
+func Drain(ctx context.Context, preStopDelay, drainTimeout time.Duration, srv *http.Server, tracker *ConnTracker, readiness *Readiness, log *logger.Logger, closers ...Closer) error {
+	readiness.Fail()
+	log.Info("Readiness probe failing, waiting for load balancers to deregister", "pre_stop_delay", preStopDelay.String())
+	time.Sleep(preStopDelay)
+
+	drainCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	stopLogging := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				log.Info("Draining HTTP connections", "open_connections", tracker.Count())
+			case <-stopLogging:
+				return
+			}
+		}
+	}()
+
+	httpErr := srv.Shutdown(drainCtx)
+	close(stopLogging)
+	if httpErr != nil {
+		log.Error("HTTP server forced to shutdown before drain completed", "error", httpErr)
+	}
+
+	var g errgroup.Group
+	for _, c := range closers {
+		c := c
+		g.Go(func() error {
+			if err := c.Func(); err != nil {
+				log.Error("Failed to close background worker", "name", c.Name, "error", err)
+				return err
+			}
+			log.Info("Closed background worker", "name", c.Name)
+			return nil
+		})
+	}
+
+	closeErr := g.Wait()
+	if httpErr != nil {
+		return httpErr
+	}
+	return closeErr
+}
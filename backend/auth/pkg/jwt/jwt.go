@@ -2,9 +2,12 @@ package jwt
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/google/uuid"
 )
 
 var (
@@ -35,6 +38,7 @@ func GenerateAccessToken(userID uint64, email, role, secret string, expiry time.
 		Email:  email,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
 			NotBefore: jwt.NewNumericDate(now),
@@ -64,6 +68,65 @@ func GenerateRefreshToken(userID uint64, secret string, expiry time.Duration) (s
 	return token.SignedString([]byte(secret))
 }
 
+// GenerateAccessTokenRS generates an access token signed with the key
+// manager's current RSA key. The key's id is carried in the token's "kid"
+// header so ValidateAccessTokenRS can select the matching public key.
+func GenerateAccessTokenRS(km *KeyManager, userID uint64, email, role, issuer string, expiry time.Duration) (string, error) {
+	key := km.signingKeyFor()
+	if key == nil {
+		return "", ErrInvalidToken
+	}
+
+	now := time.Now()
+	claims := AccessClaims{
+		UserID: userID,
+		Email:  email,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    issuer,
+			Subject:   email,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.privateKey)
+}
+
+// ValidateAccessTokenRS validates an RS256 access token against the key
+// manager, selecting the verifying key by the token's "kid" header.
+func ValidateAccessTokenRS(tokenString string, km *KeyManager) (*AccessClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &AccessClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, ErrInvalidToken
+		}
+		kid, _ := token.Header["kid"].(string)
+		pub := km.keyByKID(kid)
+		if pub == nil {
+			return nil, ErrInvalidToken
+		}
+		return pub, nil
+	})
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*AccessClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidClaims
+	}
+
+	return claims, nil
+}
+
 // ValidateAccessToken validates an access token and returns its claims
 func ValidateAccessToken(tokenString, secret string) (*AccessClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &AccessClaims{}, func(token *jwt.Token) (interface{}, error) {
@@ -114,6 +177,97 @@ func ValidateRefreshToken(tokenString, secret string) (*RefreshClaims, error) {
 	return claims, nil
 }
 
+// MFAPendingClaims represents the claims in a short-lived token issued by
+// Login when the account has MFA enabled. It proves the caller already
+// presented a valid password, without granting access on its own - it's
+// only accepted by the MFA verification endpoint.
+type MFAPendingClaims struct {
+	UserID uint64 `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateMFAPendingToken issues a short-lived token for the second step of
+// an MFA login.
+func GenerateMFAPendingToken(userID uint64, secret string, expiry time.Duration) (string, error) {
+	now := time.Now()
+	claims := MFAPendingClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "captcha-platform",
+			Subject:   "mfa_pending",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ValidateMFAPendingToken validates an MFA pending token and returns its claims.
+func ValidateMFAPendingToken(tokenString, secret string) (*MFAPendingClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MFAPendingClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*MFAPendingClaims)
+	if !ok || !token.Valid || claims.Subject != "mfa_pending" {
+		return nil, ErrInvalidClaims
+	}
+
+	return claims, nil
+}
+
+// IDTokenClaims represents the claims in an OIDC ID token. ID tokens are
+// only issued when this service is acting as its own OIDC provider, and are
+// always RS256-signed via the key manager so relying parties can verify
+// them against the published JWKS without sharing a secret.
+type IDTokenClaims struct {
+	Email string `json:"email"`
+	Nonce string `json:"nonce,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken signs an OIDC ID token for userID, scoped to audience
+// (the relying party's client_id) with the key manager's current RSA key.
+func GenerateIDToken(km *KeyManager, userID uint64, email, issuer, audience, nonce string, expiry time.Duration) (string, error) {
+	key := km.signingKeyFor()
+	if key == nil {
+		return "", ErrInvalidToken
+	}
+
+	now := time.Now()
+	claims := IDTokenClaims{
+		Email: email,
+		Nonce: nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    issuer,
+			Subject:   fmt.Sprintf("%d", userID),
+			Audience:  jwt.ClaimStrings{audience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.privateKey)
+}
+
 // ExtractTokenFromHeader extracts a JWT token from the Authorization header
 func ExtractTokenFromHeader(authHeader string) (string, error) {
 	if authHeader == "" {
@@ -0,0 +1,137 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KeyManager holds the RSA keypair currently used to sign access tokens with
+// RS256, plus a short grace-period window of previously-rotated keys so
+// tokens signed just before a rotation keep validating until they expire.
+type KeyManager struct {
+	mu       sync.RWMutex
+	current  *signingKey
+	previous []*signingKey
+	bits     int
+}
+
+type signingKey struct {
+	kid        string
+	privateKey *rsa.PrivateKey
+	retiredAt  time.Time // zero while this is the current signing key
+}
+
+// NewKeyManager creates a KeyManager with a freshly generated RSA keypair.
+func NewKeyManager(bits int) (*KeyManager, error) {
+	if bits <= 0 {
+		bits = 2048
+	}
+
+	km := &KeyManager{bits: bits}
+	if err := km.Rotate(); err != nil {
+		return nil, err
+	}
+	return km, nil
+}
+
+// Rotate generates a new signing key and retires the previous one. Retired
+// keys are kept around (see Prune) so tokens they signed keep validating
+// until those tokens expire.
+func (km *KeyManager) Rotate() error {
+	key, err := rsa.GenerateKey(rand.Reader, km.bits)
+	if err != nil {
+		return err
+	}
+
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	if km.current != nil {
+		km.current.retiredAt = time.Now()
+		km.previous = append(km.previous, km.current)
+	}
+	km.current = &signingKey{kid: uuid.New().String(), privateKey: key}
+
+	return nil
+}
+
+// Prune drops retired keys older than maxAge. Callers should pass an age
+// comfortably larger than the access token lifetime, so a key is never
+// dropped while a token it signed could still be unexpired.
+func (km *KeyManager) Prune(maxAge time.Duration) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	kept := km.previous[:0]
+	for _, k := range km.previous {
+		if time.Since(k.retiredAt) < maxAge {
+			kept = append(kept, k)
+		}
+	}
+	km.previous = kept
+}
+
+func (km *KeyManager) signingKeyFor() *signingKey {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return km.current
+}
+
+func (km *KeyManager) keyByKID(kid string) *rsa.PublicKey {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	if km.current != nil && km.current.kid == kid {
+		return &km.current.privateKey.PublicKey
+	}
+	for _, k := range km.previous {
+		if k.kid == kid {
+			return &k.privateKey.PublicKey
+		}
+	}
+	return nil
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS returns the current signing key and any still-valid retired keys as
+// a JSON Web Key Set, suitable for serving at /.well-known/jwks.json.
+func (km *KeyManager) JWKS() []JWK {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	keys := make([]JWK, 0, len(km.previous)+1)
+	if km.current != nil {
+		keys = append(keys, toJWK(km.current))
+	}
+	for _, k := range km.previous {
+		keys = append(keys, toJWK(k))
+	}
+	return keys
+}
+
+func toJWK(k *signingKey) JWK {
+	pub := k.privateKey.PublicKey
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.kid,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Validator wraps the stateless access-token validation functions with a
+// Redis-backed idle timeout: a token is rejected once it hasn't been used
+// for longer than IdleTimeout, even if it hasn't expired yet. This lets a
+// stolen-but-unused token be neutralized well before its normal expiry.
+type Validator struct {
+	redis       *redis.Client
+	idleTimeout time.Duration
+}
+
+// NewValidator creates a Validator. A nil redis client or a zero idleTimeout
+// disables the idle check, leaving plain expiry-based validation.
+func NewValidator(redisClient *redis.Client, idleTimeout time.Duration) *Validator {
+	return &Validator{redis: redisClient, idleTimeout: idleTimeout}
+}
+
+// ValidateAccessToken validates an HS256 access token and enforces the idle
+// timeout and revocation.
+func (v *Validator) ValidateAccessToken(ctx context.Context, tokenString, secret string) (*AccessClaims, error) {
+	claims, err := ValidateAccessToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := v.touch(ctx, claims.ID); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ValidateAccessTokenRS validates an RS256 access token and enforces the
+// idle timeout and revocation.
+func (v *Validator) ValidateAccessTokenRS(ctx context.Context, tokenString string, km *KeyManager) (*AccessClaims, error) {
+	claims, err := ValidateAccessTokenRS(tokenString, km)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := v.touch(ctx, claims.ID); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// RevokeJTI blacklists a single access token by its jti. ttl should be the
+// token's remaining time to live, so the blacklist entry disappears on its
+// own once the token would have expired anyway.
+func (v *Validator) RevokeJTI(ctx context.Context, jti string, ttl time.Duration) error {
+	if v.redis == nil || jti == "" || ttl <= 0 {
+		return nil
+	}
+	return v.redis.Set(ctx, "revoked_jti:"+jti, "1", ttl).Err()
+}
+
+// RevokeAllForUser records the current time as the user's "not before"
+// moment: every access token issued earlier than this is rejected from now
+// on, even if its individual jti was never blacklisted. This is what makes
+// logout-everywhere effective immediately instead of only once existing
+// tokens naturally expire.
+func (v *Validator) RevokeAllForUser(ctx context.Context, userID uint64) error {
+	if v.redis == nil {
+		return nil
+	}
+	return v.redis.Set(ctx, notBeforeKey(userID), time.Now().Format(time.RFC3339Nano), 0).Err()
+}
+
+// IsRevoked reports whether claims identify an access token that was
+// individually revoked or was issued before its user's last revoke-all.
+func (v *Validator) IsRevoked(ctx context.Context, claims *AccessClaims) (bool, error) {
+	if v.redis == nil {
+		return false, nil
+	}
+
+	if claims.ID != "" {
+		n, err := v.redis.Exists(ctx, "revoked_jti:"+claims.ID).Result()
+		if err == nil && n > 0 {
+			return true, nil
+		}
+	}
+
+	notBeforeStr, err := v.redis.Get(ctx, notBeforeKey(claims.UserID)).Result()
+	if err == nil {
+		if notBefore, parseErr := time.Parse(time.RFC3339Nano, notBeforeStr); parseErr == nil {
+			if claims.IssuedAt != nil && claims.IssuedAt.Time.Before(notBefore) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// checkRevoked aborts validation early for a blacklisted or superseded
+// token, so a revoked-but-not-yet-expired token is rejected the same way an
+// expired one is.
+func (v *Validator) checkRevoked(ctx context.Context, claims *AccessClaims) error {
+	revoked, err := v.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil
+	}
+	if revoked {
+		return ErrExpiredToken
+	}
+	return nil
+}
+
+func notBeforeKey(userID uint64) string {
+	return fmt.Sprintf("user_not_before:%d", userID)
+}
+
+// touch checks the token's last-used timestamp against the idle timeout and
+// then refreshes it to now, a GET followed by a SET on every validation
+// call.
+func (v *Validator) touch(ctx context.Context, jti string) error {
+	if v.redis == nil || v.idleTimeout <= 0 || jti == "" {
+		return nil
+	}
+
+	key := "token_last_used:" + jti
+	lastUsedStr, err := v.redis.Get(ctx, key).Result()
+	if err == nil {
+		if lastUsed, parseErr := time.Parse(time.RFC3339Nano, lastUsedStr); parseErr == nil {
+			if time.Since(lastUsed) > v.idleTimeout {
+				return ErrExpiredToken
+			}
+		}
+	}
+
+	// Keep the key around for a bit longer than the idle window itself, so a
+	// token sitting exactly at the edge of its budget doesn't get treated as
+	// brand new just because Redis expired the tracking key first.
+	v.redis.Set(ctx, key, time.Now().Format(time.RFC3339Nano), v.idleTimeout*2)
+	return nil
+}
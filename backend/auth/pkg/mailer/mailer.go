@@ -0,0 +1,59 @@
+// Package mailer sends transactional email (address verification, password
+// reset) over SMTP.
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+)
+
+// Message is a single plaintext transactional email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a Message. SMTPSender is the only production
+// implementation; callers that want the feature to degrade gracefully when
+// no relay is configured should hold a nil Sender instead of a no-op one.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// Config holds SMTP relay connection settings.
+type Config struct {
+	Host     string
+	Port     int
+	Username string // empty disables SMTP AUTH, e.g. for a local relay
+	Password string
+	From     string
+}
+
+// SMTPSender sends mail through an SMTP relay, authenticating with PLAIN
+// auth when Config.Username is set.
+type SMTPSender struct {
+	cfg Config
+}
+
+// NewSMTPSender creates a new SMTPSender.
+func NewSMTPSender(cfg Config) *SMTPSender {
+	return &SMTPSender{cfg: cfg}
+}
+
+// Send delivers msg via the configured SMTP relay.
+func (s *SMTPSender) Send(msg Message) error {
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	body := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		s.cfg.From, msg.To, msg.Subject, msg.Body,
+	)
+
+	return smtp.SendMail(addr, auth, s.cfg.From, []string{msg.To}, []byte(body))
+}
@@ -0,0 +1,52 @@
+package scopes
+
+import "testing"
+
+func TestGrants(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  string
+		required string
+		want     bool
+	}{
+		{"exact match", "captcha:solve", "captcha:solve", true},
+		{"sibling leaf scope not granted", "captcha:solve", "captcha:solve:batch", false},
+		{"reverse sibling not granted", "captcha:solve:batch", "captcha:solve", false},
+		{"unrelated scope", "models:read", "models:write", false},
+		{"wildcard grants child", "admin:*", "admin:users:write", true},
+		{"wildcard grants itself", "admin:*", "admin:*", true},
+		{"wildcard does not grant unrelated prefix", "admin:*", "models:read", false},
+		{"non-wildcard scope grants nothing else", "admin", "admin:users:write", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Grants(tt.granted, tt.required); got != tt.want {
+				t.Errorf("Grants(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnyGrants(t *testing.T) {
+	granted := []string{"captcha:solve", "stats:read"}
+
+	if !AnyGrants(granted, "stats:read") {
+		t.Error("expected stats:read to be granted")
+	}
+	if AnyGrants(granted, "captcha:solve:batch") {
+		t.Error("captcha:solve should not grant the sibling captcha:solve:batch scope")
+	}
+	if AnyGrants(nil, "stats:read") {
+		t.Error("no granted scopes should never grant anything")
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !Valid("captcha:solve") {
+		t.Error("expected captcha:solve to be a valid canonical scope")
+	}
+	if Valid("captcha:nonexistent") {
+		t.Error("expected an unknown scope to be invalid")
+	}
+}
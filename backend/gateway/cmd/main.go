@@ -2,20 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
 	"time"
 
+	"github.com/captcha-platform/gateway/internal/audit"
 	"github.com/captcha-platform/gateway/internal/config"
 	"github.com/captcha-platform/gateway/internal/handlers"
+	"github.com/captcha-platform/gateway/internal/metrics"
 	"github.com/captcha-platform/gateway/internal/middleware"
 	"github.com/captcha-platform/gateway/internal/proxy"
+	"github.com/captcha-platform/gateway/internal/shutdown"
+	"github.com/captcha-platform/gateway/internal/telemetry"
+	"github.com/captcha-platform/gateway/pkg/jwks"
 	"github.com/captcha-platform/gateway/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
@@ -36,31 +46,165 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Exports to cfg.Telemetry.OTLPEndpoint when configured; otherwise
+	// spans are still created and propagated (so RequestID can adopt a
+	// trace ID and the proxy still forwards traceparent/tracestate) but
+	// never leave the process.
+	shutdownTracing := telemetry.Init("gateway", cfg.Telemetry.OTLPEndpoint, log)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("Failed to shut down tracing", "error", err)
+		}
+	}()
+
 	// Initialize Redis client for rate limiting
 	redisClient, err := middleware.NewRedisClient(cfg.Redis)
 	if err != nil {
 		log.Warn("Failed to connect to Redis, using in-memory rate limiting", "error", err)
 	}
 
-	// Initialize service proxies
-	authProxy := proxy.NewServiceProxy(cfg.Services.AuthURL, log)
-	captchaProxy := proxy.NewServiceProxy(cfg.Services.CaptchaURL, log)
+	// The sliding-window limiter enforces a quota cluster-wide across every
+	// gateway instance; the token-bucket fallback only sees this process's
+	// traffic, same tradeoff the rest of the gateway makes when Redis is
+	// unavailable.
+	var limiter middleware.Limiter
+	var challengeStore middleware.ChallengeStore
+	if redisClient != nil {
+		limiter = middleware.NewRedisSlidingWindowLimiter(redisClient)
+		challengeStore = middleware.NewRedisChallengeStore(redisClient)
+	} else {
+		limiter = middleware.NewTokenBucketLimiter()
+		challengeStore = middleware.NewInMemoryChallengeStore()
+	}
+
+	// Build the audit sink the operator configured - stdout by default, or
+	// a local rotating file or HTTP collector. A Kafka sink isn't built in,
+	// but any type implementing audit.Sink plugs in the same way.
+	var auditSink audit.Sink
+	switch cfg.Audit.Sink {
+	case "file":
+		fileSink, err := audit.NewRotatingFileSink(cfg.Audit.FilePath, cfg.Audit.FileMaxBytes)
+		if err != nil {
+			log.Fatal("Failed to open audit log file", "error", err, "path", cfg.Audit.FilePath)
+		}
+		auditSink = fileSink
+	case "http":
+		auditSink = audit.NewHTTPSink(cfg.Audit.HTTPSinkURL)
+	default:
+		auditSink = audit.NewStdoutSink(os.Stdout)
+	}
+	auditLogger := audit.NewLogger(log, auditSink)
+	defer auditLogger.Stop()
+	auditRule := audit.DefaultRedactionRule()
+	if cfg.Audit.RedactPattern != "" {
+		// Combined as an alternation with the built-in pattern rather than
+		// replacing it, so a custom pattern only adds coverage instead of
+		// silently dropping the default password/token/secret/authorization
+		// redaction.
+		pattern, err := regexp.Compile(auditRule.Pattern.String() + "|" + cfg.Audit.RedactPattern)
+		if err != nil {
+			log.Fatal("Invalid AUDIT_REDACT_PATTERN", "error", err)
+		}
+		auditRule.Pattern = pattern
+	}
+	auditRedactor := audit.NewRedactor(auditRule)
+
+	// When enabled, every proxy in the pool dials its upstream over mutual
+	// TLS instead of plain HTTP, so the X-User-* identity headers set below
+	// can't be spoofed by a caller that reaches the backend directly - see
+	// proxy.MTLSSource and the auth service's middleware.RequirePeerSPIFFE.
+	mtlsSource, err := proxy.NewMTLSSource(context.Background(), proxy.MTLSConfig{
+		Enabled:          cfg.UpstreamMTLS.Enabled,
+		CertFile:         cfg.UpstreamMTLS.CertFile,
+		KeyFile:          cfg.UpstreamMTLS.KeyFile,
+		CACertFile:       cfg.UpstreamMTLS.CACertFile,
+		UseSPIFFE:        cfg.UpstreamMTLS.UseSPIFFE,
+		SPIFFESocketPath: cfg.UpstreamMTLS.SPIFFESocketPath,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to initialize upstream mTLS", "error", err)
+	}
+
+	// Initialize service proxies. A shared pool means any future route that
+	// proxies to one of these same upstreams reuses its breaker/Transport
+	// instead of standing up a new one.
+	proxyPool := proxy.NewPool(log, mtlsSource)
+	authProxy, err := proxyPool.Get(cfg.Services.AuthURL)
+	if err != nil {
+		log.Fatal("Failed to initialize auth service proxy", "error", err)
+	}
+	captchaProxy, err := proxyPool.Get(cfg.Services.CaptchaURL)
+	if err != nil {
+		log.Fatal("Failed to initialize captcha service proxy", "error", err)
+	}
+
+	// Feed each proxy's circuit breaker a periodic health probe so it
+	// recovers on its own once a tripped upstream comes back.
+	healthCtx, stopHealthMonitors := context.WithCancel(context.Background())
+	defer stopHealthMonitors()
+	for _, sp := range proxyPool.All() {
+		go sp.StartHealthMonitor(healthCtx, 15*time.Second)
+	}
+
+	// When configured for RS256, JWTs are verified against the auth
+	// service's published keys instead of the shared HS256 secret.
+	var jwksClient *jwks.Client
+	if cfg.JWT.SigningMethod == "RS256" {
+		jwksClient = jwks.NewClient(cfg.Services.AuthURL+"/.well-known/jwks.json", cfg.JWT.JWKSCacheTTL)
+	}
+
+	// The verifier also consults redisClient (when available) so a /logout
+	// on the auth service is honored at the gateway immediately, instead of
+	// only once a revoked token naturally expires.
+	verifier := middleware.NewVerifier(jwksClient, cfg.JWT.Secret, cfg.JWT.Issuer, cfg.JWT.Audience, redisClient)
+
+	// Initialize API key introspector, used to resolve scopes and per-key
+	// rate limits for API-key authenticated requests
+	apiKeyIntrospector := middleware.NewAPIKeyIntrospector(cfg.Services.AuthURL)
+
+	// Batches total_requests/last_used_at increments for API-key requests
+	// instead of writing to the auth service's DB on every request
+	usageRecorder := middleware.NewUsageRecorder(cfg.Services.AuthURL, cfg.APIKeyUsage.FlushInterval, cfg.APIKeyUsage.FlushBatchSize)
+	defer usageRecorder.Stop()
 
 	// Initialize handlers
 	proxyHandler := handlers.NewProxyHandler(authProxy, captchaProxy, log)
+	debugHandler := handlers.NewDebugHandler(proxyPool)
 
 	// Setup Gin router
 	router := gin.New()
 
 	// Add global middleware
 	router.Use(gin.Recovery())
+	// otelgin starts the request's span (and decodes an inbound
+	// traceparent header into it) before RequestID runs, so RequestID can
+	// adopt the trace ID; it runs before metrics.Middleware so the
+	// histogram observation covers the full request.
+	router.Use(otelgin.Middleware("gateway"))
+	router.Use(metrics.Middleware())
 	router.Use(middleware.Logger(log))
 	router.Use(middleware.RequestID())
 	router.Use(middleware.CORS(cfg.CORS))
 	router.Use(middleware.SecurityHeaders())
+	if cfg.Audit.Enabled {
+		router.Use(middleware.AuditLog(auditLogger, auditRedactor, middleware.CaptureConfig{
+			CaptureRequestBody:  cfg.Audit.CaptureRequestBody,
+			CaptureResponseBody: cfg.Audit.CaptureResponseBody,
+			MinStatus:           cfg.Audit.MinStatus,
+			SampleRate:          cfg.Audit.SampleRate,
+		}))
+	}
+
+	// readiness fails as soon as a shutdown signal is received, before the
+	// drain's pre-stop delay, so /readyz can deregister the pod from load
+	// balancers ahead of the HTTP server actually stopping.
+	readiness := shutdown.NewReadiness()
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
+	// Liveness - whether the process itself is up. Never fails during a
+	// graceful drain; k8s should only act on this to restart a wedged pod.
+	router.GET("/livez", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "healthy",
 			"service": "gateway",
@@ -68,17 +212,45 @@ func main() {
 		})
 	})
 
+	// Readiness - whether this instance should receive new traffic.
+	router.GET("/readyz", func(c *gin.Context) {
+		if !readiness.OK() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "shutting_down",
+				"service": "gateway",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ready",
+			"service": "gateway",
+			"time":    time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/debug/certs", debugHandler.Certs)
+
 	// API routes
 	api := router.Group("/api")
 	{
 		v1 := api.Group("/v1")
 		{
-			// Rate limiting for all v1 routes
-			if redisClient != nil {
-				v1.Use(middleware.RateLimiterRedis(redisClient, cfg.RateLimit.Requests, cfg.RateLimit.Window))
-			} else {
-				v1.Use(middleware.RateLimiterMemory(cfg.RateLimit.Requests))
+			// Rate limiting for all v1 routes, keyed by client IP. This runs
+			// ahead of any route's own auth middleware, so there's no
+			// caller identity yet to give an Authenticated quota to - routes
+			// that want one (e.g. captcha solve below) apply their own
+			// Policy further down the chain, after auth has run.
+			v1Policy := middleware.Policy{
+				Name:      "v1-global",
+				Extractor: middleware.ExtractClientIP,
+				Anonymous: middleware.Quota{
+					Limit:  cfg.RateLimit.Requests,
+					Burst:  cfg.RateLimit.Burst,
+					Window: cfg.RateLimit.Window,
+				},
 			}
+			v1.Use(middleware.RateLimit(limiter, challengeStore, v1Policy))
 
 			// Auth routes - proxy to auth service
 			auth := v1.Group("/auth")
@@ -90,7 +262,7 @@ func main() {
 
 				// Protected auth routes
 				authProtected := auth.Group("")
-				authProtected.Use(middleware.AuthRequired(cfg.JWT.Secret))
+				authProtected.Use(middleware.AuthRequired(verifier))
 				{
 					authProtected.GET("/me", proxyHandler.ProxyToAuth)
 					authProtected.PUT("/me", proxyHandler.ProxyToAuth)
@@ -98,9 +270,11 @@ func main() {
 				}
 			}
 
-			// API Keys routes - proxy to auth service (protected)
+			// API Keys routes - proxy to auth service (protected). Accepts
+			// either a JWT (self-service management of your own keys) or an
+			// API key carrying the admin:* scope.
 			apiKeys := v1.Group("/api-keys")
-			apiKeys.Use(middleware.AuthRequired(cfg.JWT.Secret))
+			apiKeys.Use(middleware.APIKeyOrJWTAuth(verifier, apiKeyIntrospector, usageRecorder, cfg.TLS), middleware.RequireScope("admin:*"))
 			{
 				apiKeys.GET("", proxyHandler.ProxyToAuth)
 				apiKeys.POST("", proxyHandler.ProxyToAuth)
@@ -110,13 +284,49 @@ func main() {
 			// Captcha routes - proxy to captcha service
 			captcha := v1.Group("/captcha")
 			{
+				// Keyed by API-key hash (not client IP) so a shared NAT or
+				// proxy in front of many callers can't let one key starve
+				// another's quota. Authenticated is the default for a key
+				// with no per-key limit of its own; LimitContextKey lets a
+				// key's own api_key_rate_limit (resolved by
+				// APIKeyOrJWTAuth/APIKeyIntrospector) override it.
+				// ChallengeEnabled routes, when configured, a blocked
+				// caller to a captcha challenge instead of a hard 429.
+				captchaSolvePolicy := middleware.Policy{
+					Name:      "v1-captcha-solve",
+					Extractor: middleware.ExtractAPIKeyHash,
+					Anonymous: middleware.Quota{
+						Limit:  cfg.RateLimit.Requests,
+						Burst:  cfg.RateLimit.Burst,
+						Window: cfg.RateLimit.Window,
+					},
+					Authenticated: middleware.Quota{
+						Limit:  cfg.RateLimit.AuthenticatedRequests,
+						Burst:  cfg.RateLimit.Burst,
+						Window: cfg.RateLimit.Window,
+					},
+					LimitContextKey: "api_key_rate_limit",
+					Challenge: middleware.ChallengeConfig{
+						Enabled: cfg.RateLimit.ChallengeEnabled,
+						TTL:     cfg.RateLimit.ChallengeTTL,
+					},
+				}
+
 				// Public route for solving (with API key auth)
-				captcha.POST("/solve", middleware.APIKeyOrJWTAuth(cfg.JWT.Secret), proxyHandler.ProxyToCaptcha)
-				captcha.POST("/solve/batch", middleware.APIKeyOrJWTAuth(cfg.JWT.Secret), proxyHandler.ProxyToCaptcha)
+				captcha.POST("/solve",
+					middleware.APIKeyOrJWTAuth(verifier, apiKeyIntrospector, usageRecorder, cfg.TLS),
+					middleware.RequireScope("captcha:solve"),
+					middleware.RateLimit(limiter, challengeStore, captchaSolvePolicy),
+					proxyHandler.ProxyToCaptcha)
+				captcha.POST("/solve/batch",
+					middleware.APIKeyOrJWTAuth(verifier, apiKeyIntrospector, usageRecorder, cfg.TLS),
+					middleware.RequireScope("captcha:solve:batch"),
+					middleware.RateLimit(limiter, challengeStore, captchaSolvePolicy),
+					proxyHandler.ProxyToCaptcha)
 
 				// Protected routes
 				captchaProtected := captcha.Group("")
-				captchaProtected.Use(middleware.AuthRequired(cfg.JWT.Secret))
+				captchaProtected.Use(middleware.AuthRequired(verifier))
 				{
 					captchaProtected.GET("/models", proxyHandler.ProxyToCaptcha)
 					captchaProtected.POST("/models/upload", proxyHandler.ProxyToCaptcha)
@@ -129,6 +339,10 @@ func main() {
 		}
 	}
 
+	// Tracks open connections so the shutdown drain can log how many remain
+	// once a second while it waits for them to close.
+	connTracker := &shutdown.ConnTracker{}
+
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -136,13 +350,29 @@ func main() {
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
+		ConnState:    connTracker.ConnState,
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := buildTLSConfig(cfg.TLS)
+		if err != nil {
+			log.Fatal("Failed to configure TLS", "error", err)
+		}
+		srv.TLSConfig = tlsConfig
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Info("API Gateway started", "port", cfg.Port, "environment", cfg.Environment)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Failed to start server", "error", err)
+		log.Info("API Gateway started", "port", cfg.Port, "environment", cfg.Environment, "tls", cfg.TLS.Enabled)
+
+		var serveErr error
+		if cfg.TLS.Enabled {
+			serveErr = srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			serveErr = srv.ListenAndServe()
+		}
+		if serveErr != nil && serveErr != http.ErrServerClosed {
+			log.Fatal("Failed to start server", "error", serveErr)
 		}
 	}()
 
@@ -153,19 +383,52 @@ func main() {
 
 	log.Info("Shutting down API Gateway...")
 
-	// Create a deadline to wait for
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Close Redis connection
+	var closers []shutdown.Closer
 	if redisClient != nil {
-		redisClient.Close()
+		closers = append(closers, shutdown.Closer{Name: "redis", Func: redisClient.Close})
+	}
+	if mtlsSource != nil {
+		closers = append(closers, shutdown.Closer{Name: "upstream_mtls", Func: mtlsSource.Close})
 	}
 
-	// Attempt graceful shutdown
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown", "error", err)
+	// A drain error just means the deadline hit before everything finished
+	// (or a closer failed) - log it and exit cleanly anyway, the process is
+	// going down either way and a Fatal here would itself abandon whatever
+	// the drain hadn't finished.
+	if err := shutdown.Drain(context.Background(), cfg.Shutdown.PreStopDelay, cfg.Shutdown.DrainTimeout, srv, connTracker, readiness, log, closers...); err != nil {
+		log.Error("Shutdown did not complete cleanly", "error", err)
 	}
 
 	log.Info("API Gateway stopped")
-}
\ No newline at end of file
+}
+
+// buildTLSConfig sets up the server's TLS listener. When a client CA bundle
+// is configured it also enables mTLS, verifying client certificates against
+// that bundle so routes can accept a certificate as an alternative to an
+// API key (see middleware.APIKeyOrJWTAuth).
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caCert, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse client CA file %q", cfg.ClientCAFile)
+	}
+
+	tlsConfig.ClientCAs = caPool
+	if cfg.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,131 @@
+// Package audit records one structured entry per proxied request - method,
+// path, status, latency, caller identity, the upstream it was routed to,
+// and (subject to sampling and redaction) its headers and body - so an
+// incident investigation has more to go on than the one-line entries
+// middleware.Logger writes for every request.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Record is one audited request/response pair. Body/header fields are
+// omitted (empty) when capture wasn't enabled or sampling skipped them, and
+// are redacted by a Redactor before they ever reach a Record.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	RequestID string    `json:"request_id"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	SpanID    string    `json:"span_id,omitempty"`
+
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Query    string `json:"query,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
+
+	Status    int   `json:"status"`
+	LatencyMS int64 `json:"latency_ms"`
+
+	ClientIP   string `json:"client_ip"`
+	AuthType   string `json:"auth_type,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	APIKeyHash string `json:"api_key_hash,omitempty"`
+
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+}
+
+// Sink persists or forwards audit Records - to stdout, a rotating file, an
+// HTTP collector, or (by implementing this interface outside the package) a
+// Kafka topic.
+type Sink interface {
+	Write(ctx context.Context, rec Record) error
+}
+
+// errorLogger is the subset of pkg/logger.Logger that Logger needs, so this
+// package doesn't import it directly and create a dependency cycle risk.
+type errorLogger interface {
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// Logger fans a Record out to every configured Sink on a background
+// goroutine, so a slow or unreachable sink (an HTTP collector, say) never
+// adds latency to the request that triggered it.
+type Logger struct {
+	sinks []Sink
+	log   errorLogger
+	queue chan Record
+	done  chan struct{}
+}
+
+// NewLogger creates a Logger that fans each Record out to sinks. Errors
+// from a Sink are reported via log rather than returned, since by the time
+// a Record reaches here the request it describes has already completed.
+func NewLogger(log errorLogger, sinks ...Sink) *Logger {
+	l := &Logger{
+		sinks: sinks,
+		log:   log,
+		queue: make(chan Record, 1024),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Log queues rec for delivery to every sink. It never blocks the request
+// path - if the queue is full, the record is dropped (and the drop itself
+// logged) rather than stalling the caller.
+func (l *Logger) Log(rec Record) {
+	select {
+	case l.queue <- rec:
+	default:
+		l.log.Error("Audit log queue full, dropping record", "request_id", rec.RequestID)
+	}
+}
+
+// Stop tells the background goroutine to deliver every record still queued
+// and then exit. It returns without waiting for that delivery.
+func (l *Logger) Stop() {
+	close(l.done)
+}
+
+func (l *Logger) run() {
+	for {
+		select {
+		case rec := <-l.queue:
+			l.deliver(rec)
+		case <-l.done:
+			for {
+				select {
+				case rec := <-l.queue:
+					l.deliver(rec)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *Logger) deliver(rec Record) {
+	ctx := context.Background()
+	for _, sink := range l.sinks {
+		if err := sink.Write(ctx, rec); err != nil {
+			l.log.Error("Failed to write audit record", "error", err, "request_id", rec.RequestID)
+		}
+	}
+}
+
+// marshal renders rec as a single JSON line, as every built-in Sink but
+// HTTPSink writes it.
+func marshal(rec Record) ([]byte, error) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const redactedPlaceholder = "[REDACTED]"
+
+// defaultRedactPattern catches the common key=value and header-style
+// encodings Pointers can't reach because the body never parses as JSON -
+// form-encoded login bodies, plain-text error responses, or a JSON body
+// that fails to parse. The value is matched up to the next "&" (a
+// form-encoding field separator) or newline rather than stopping at the
+// first space, so a multi-word value like "Authorization: Bearer <token>"
+// is redacted in full instead of just its first token.
+var defaultRedactPattern = regexp.MustCompile(`(?i)(password|token|secret|authorization)[=:]\s*[^&\n]+`)
+
+// RedactionRule controls what Redactor masks before a request/response ever
+// reaches a Record: header names whose values are masked, JSON pointers into
+// a structured body to mask in place, and a fallback regex for bodies that
+// aren't JSON. The regex is not applied to a body that parses as JSON.
+type RedactionRule struct {
+	Headers  []string       // header names masked, case-insensitive
+	Pointers []string       // JSON pointers (e.g. "/password", "/user/ssn") masked within a JSON body
+	Pattern  *regexp.Regexp // applied to non-JSON bodies; every match is replaced with the placeholder
+}
+
+// DefaultRedactionRule masks the credential fields every route in this
+// gateway can plausibly see: the Authorization/Cookie headers, the
+// X-API-Key header (the raw key, not its hash - see ExtractAPIKeyHash),
+// the password/token fields the auth service's request/response bodies
+// carry, and - via Pattern - the same fields when the body isn't JSON.
+func DefaultRedactionRule() RedactionRule {
+	return RedactionRule{
+		Headers: []string{
+			"Authorization",
+			"Cookie",
+			"Set-Cookie",
+			"X-Api-Key",
+		},
+		Pointers: []string{
+			"/password",
+			"/new_password",
+			"/current_password",
+			"/token",
+			"/access_token",
+			"/refresh_token",
+			"/secret",
+			"/client_secret",
+			"/totp_code",
+			"/recovery_code",
+		},
+		Pattern: defaultRedactPattern,
+	}
+}
+
+// Redactor applies a RedactionRule to request/response headers and bodies
+// before they're embedded in a Record.
+type Redactor struct {
+	rule         RedactionRule
+	maskedHeader map[string]bool
+}
+
+// NewRedactor creates a Redactor enforcing rule.
+func NewRedactor(rule RedactionRule) *Redactor {
+	masked := make(map[string]bool, len(rule.Headers))
+	for _, name := range rule.Headers {
+		masked[strings.ToLower(name)] = true
+	}
+	return &Redactor{rule: rule, maskedHeader: masked}
+}
+
+// Headers returns h as a map, replacing any header in the redaction rule
+// with the placeholder rather than its actual value.
+func (r *Redactor) Headers(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		if r.maskedHeader[strings.ToLower(name)] {
+			value = redactedPlaceholder
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// Body redacts body: if it parses as JSON, every configured Pointer is
+// masked in place and the result re-marshaled; otherwise Pattern (if set)
+// is applied to the raw bytes. A body that's neither valid JSON nor
+// matched by Pattern is returned unchanged - the caller is responsible for
+// size-capping before this is ever called.
+func (r *Redactor) Body(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		for _, pointer := range r.rule.Pointers {
+			redactPointer(parsed, pointer)
+		}
+		if redacted, err := json.Marshal(parsed); err == nil {
+			return string(redacted)
+		}
+	}
+
+	if r.rule.Pattern != nil {
+		return r.rule.Pattern.ReplaceAllString(string(body), redactedPlaceholder)
+	}
+	return string(body)
+}
+
+// redactPointer masks the value at pointer (a "/"-separated path, RFC 6901
+// style) within doc in place, if present. doc must be the result of
+// json.Unmarshal into an interface{} (so object levels are
+// map[string]interface{}).
+func redactPointer(doc interface{}, pointer string) {
+	segments := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	if len(segments) == 0 {
+		return
+	}
+
+	cur := doc
+	for i, segment := range segments {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if i == len(segments)-1 {
+			if _, exists := obj[segment]; exists {
+				obj[segment] = redactedPlaceholder
+			}
+			return
+		}
+		cur, ok = obj[segment]
+		if !ok {
+			return
+		}
+	}
+}
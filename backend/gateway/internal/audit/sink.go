@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// StdoutSink writes one JSON line per Record to an io.Writer, typically
+// os.Stdout - the default for a deployment that ships logs off-box via its
+// container runtime rather than writing to disk itself.
+type StdoutSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutSink creates a StdoutSink writing to w.
+func NewStdoutSink(w io.Writer) *StdoutSink {
+	return &StdoutSink{w: w}
+}
+
+// Write implements Sink.
+func (s *StdoutSink) Write(_ context.Context, rec Record) error {
+	line, err := marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// RotatingFileSink writes one JSON line per Record to a local file,
+// rotating it (renaming the current file aside with a ".1" suffix,
+// discarding any prior ".1") once it exceeds maxBytes.
+type RotatingFileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+// NewRotatingFileSink opens (creating if needed) a RotatingFileSink at
+// path, rotating once the file exceeds maxBytes. maxBytes <= 0 disables
+// rotation.
+func NewRotatingFileSink(path string, maxBytes int64) (*RotatingFileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("audit: failed to open %q: %w", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &RotatingFileSink{path: path, maxBytes: maxBytes, file: f, size: info.Size()}, nil
+}
+
+// Write implements Sink.
+func (s *RotatingFileSink) Write(_ context.Context, rec Record) error {
+	line, err := marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// rotate closes the current file, renames it aside with a ".1" suffix
+// (replacing any previous one), and opens a fresh file at path. Caller must
+// hold s.mu.
+func (s *RotatingFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	s.file = f
+	s.size = 0
+	return nil
+}
+
+// Close closes the underlying file.
+func (s *RotatingFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// HTTPSink POSTs each Record as JSON to a collector URL - e.g. a
+// log-shipping proxy that fans out to Kafka, Elasticsearch, or whatever
+// else the operator's pipeline expects.
+type HTTPSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewHTTPSink creates an HTTPSink posting to url.
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{url: url, httpClient: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Write implements Sink.
+func (s *HTTPSink) Write(ctx context.Context, rec Record) error {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit: sink %s returned status %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
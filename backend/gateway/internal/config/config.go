@@ -10,14 +10,131 @@ import (
 
 // Config holds all configuration for the gateway
 type Config struct {
-	Port        int
-	Environment string
-	JWT         JWTConfig
-	CORS        CORSConfig
-	RateLimit   RateLimitConfig
-	Redis       RedisConfig
-	Services    ServicesConfig
-	LogLevel    string
+	Port         int
+	Environment  string
+	JWT          JWTConfig
+	CORS         CORSConfig
+	RateLimit    RateLimitConfig
+	Redis        RedisConfig
+	Services     ServicesConfig
+	TLS          TLSConfig
+	LogLevel     string
+	APIKeyUsage  APIKeyUsageConfig
+	Audit        AuditConfig
+	Telemetry    TelemetryConfig
+	Shutdown     ShutdownConfig
+	UpstreamMTLS UpstreamMTLSConfig
+}
+
+// UpstreamMTLSConfig controls mutual TLS between the gateway and its
+// backend services. The gateway sets X-User-ID/X-User-Role/X-API-Key-Hash
+// identity headers for a backend to trust - over plain HTTP anything that
+// can reach the backend directly can spoof them, so enabling this lets the
+// backend require the connection itself to be an mTLS session from an
+// allow-listed gateway identity (see the auth service's
+// middleware.RequirePeerSPIFFE) before it honors those headers.
+type UpstreamMTLSConfig struct {
+	Enabled bool
+
+	// Static cert/key mode, used when UseSPIFFE is false.
+	CertFile   string
+	KeyFile    string
+	CACertFile string
+
+	// SPIFFE Workload API mode - fetches and auto-rotates the client SVID
+	// and trust bundle instead of reading static files.
+	UseSPIFFE        bool
+	SPIFFESocketPath string
+}
+
+// ShutdownConfig controls the graceful-drain sequence run on SIGINT/SIGTERM.
+// /readyz starts failing immediately; PreStopDelay then gives upstream load
+// balancers time to deregister the pod before the HTTP server stops
+// accepting new connections, and DrainTimeout bounds how long in-flight
+// requests get to finish. Background workers are closed after that, with
+// no deadline of their own.
+type ShutdownConfig struct {
+	PreStopDelay time.Duration
+	DrainTimeout time.Duration
+}
+
+// TelemetryConfig controls OpenTelemetry trace export. OTLPEndpoint empty
+// means tracing stays local (spans are created and propagated but never
+// exported) - see telemetry.Init.
+type TelemetryConfig struct {
+	OTLPEndpoint string
+}
+
+// AuditConfig controls the AuditLog middleware: which Sink it ships
+// records to, how much of a request/response body it captures, and under
+// what conditions.
+type AuditConfig struct {
+	Enabled bool
+
+	// CaptureRequestBody/CaptureResponseBody gate body capture outright;
+	// MinStatus and SampleRate further restrict which captured-eligible
+	// requests actually get a body in their record (see
+	// middleware.CaptureConfig).
+	CaptureRequestBody  bool
+	CaptureResponseBody bool
+	MinStatus           int
+	SampleRate          float64
+
+	// Sink selects where records are shipped: "stdout" (default), "file",
+	// or "http".
+	Sink         string
+	FilePath     string
+	FileMaxBytes int64
+	HTTPSinkURL  string
+
+	// RedactPattern, if set, is ORed onto audit.DefaultRedactionRule's
+	// built-in regex for masking non-JSON request/response bodies
+	// (form-encoded bodies, plain-text errors, a JSON body that fails to
+	// parse) - it adds coverage rather than replacing the default.
+	RedactPattern string
+}
+
+// APIKeyUsageConfig controls how the gateway batches API-key usage
+// (total_requests/last_used_at) before reporting it to the auth service,
+// so a busy key doesn't cost a DB write per request.
+type APIKeyUsageConfig struct {
+	FlushInterval  time.Duration // max time an increment waits before being flushed
+	FlushBatchSize int           // flush early once this many increments have buffered
+}
+
+// TLSConfig holds the settings for serving HTTPS and, optionally, verifying
+// client certificates (mTLS) as an alternative to API keys.
+type TLSConfig struct {
+	Enabled           bool
+	CertFile          string
+	KeyFile           string
+	ClientCAFile      string // CA bundle used to verify client certificates; empty disables mTLS
+	RequireClientCert bool   // if true, reject the handshake outright without a valid client cert
+
+	// AllowedCNs restricts which client certificate subject CNs
+	// APIKeyOrJWTAuth will accept, on top of chain validation against
+	// ClientCAFile. Empty means any CN signed by the CA is accepted, which
+	// is only safe when the CA issues certs exclusively to trusted callers.
+	AllowedCNs []string
+
+	// RevokedCNs denylists specific CNs even though their certificate still
+	// chains to ClientCAFile - the cheapest way to shut off a compromised
+	// cert without reissuing the CA or maintaining a CRL.
+	RevokedCNs []string
+
+	// ClientIdentities maps a client cert's CN to the local identity and
+	// scopes it authenticates as, so mTLS callers resolve to a user_id and
+	// scope set the same way an API key does instead of just asserting an
+	// opaque CN downstream. A CN with mTLS access but no entry here
+	// authenticates with no user_id and no scopes.
+	ClientIdentities map[string]ClientCertIdentity
+}
+
+// ClientCertIdentity is the identity and scopes granted to a client
+// certificate CN, as configured in TLSConfig.ClientIdentities.
+type ClientCertIdentity struct {
+	UserID uint64
+	Scopes []string
 }
 
 // JWTConfig holds JWT configuration
@@ -25,6 +142,10 @@ type JWTConfig struct {
 	Secret           string
 	ExpiresIn        time.Duration
 	RefreshExpiresIn time.Duration
+	SigningMethod    string        // "HS256" (default) or "RS256"
+	JWKSCacheTTL     time.Duration // how long a fetched JWKS is cached before refetching
+	Issuer           string        // expected "iss" claim; empty disables the check. The auth service mints access tokens with different issuers for normal login (JWT_ISSUER) vs its OIDC provider flow (OIDC_PROVIDER_ISSUER), so this is opt-in rather than defaulted
+	Audience         string        // expected "aud" claim; empty disables the check (access tokens don't set one today)
 }
 
 // CORSConfig holds CORS configuration
@@ -34,10 +155,20 @@ type CORSConfig struct {
 	AllowedHeaders []string
 }
 
-// RateLimitConfig holds rate limiting configuration
+// RateLimitConfig holds rate limiting configuration. Requests/Window are the
+// default v1 policy's quota (it has no authenticated tier) and the captcha
+// solve policy's anonymous quota; AuthenticatedRequests is the captcha
+// solve policy's quota for callers APIKeyOrJWTAuth identified (a JWT, API
+// key, or mTLS cert), which typically gets a higher allowance than an
+// anonymous caller sharing a NAT'd IP. ChallengeEnabled/ChallengeTTL govern the
+// captcha/solve policy's "challenge instead of 429" mode.
 type RateLimitConfig struct {
-	Requests int
-	Window   time.Duration
+	Requests              int
+	AuthenticatedRequests int
+	Burst                 int
+	Window                time.Duration
+	ChallengeEnabled      bool
+	ChallengeTTL          time.Duration
 }
 
 // RedisConfig holds Redis configuration
@@ -70,6 +201,10 @@ func Load() (*Config, error) {
 			Secret:           getEnv("JWT_SECRET", "your-secret-key-min-32-characters-long"),
 			ExpiresIn:        getEnvDuration("JWT_EXPIRES_IN", 24*time.Hour),
 			RefreshExpiresIn: getEnvDuration("JWT_REFRESH_EXPIRES_IN", 7*24*time.Hour),
+			SigningMethod:    strings.ToUpper(getEnv("JWT_SIGNING_METHOD", "HS256")),
+			JWKSCacheTTL:     getEnvDuration("JWT_JWKS_CACHE_TTL", 5*time.Minute),
+			Issuer:           getEnv("JWT_ISSUER", ""),
+			Audience:         getEnv("JWT_AUDIENCE", ""),
 		},
 
 		CORS: CORSConfig{
@@ -86,8 +221,12 @@ func Load() (*Config, error) {
 		},
 
 		RateLimit: RateLimitConfig{
-			Requests: getEnvInt("RATE_LIMIT_REQUESTS", 100),
-			Window:   time.Duration(getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60)) * time.Second,
+			Requests:              getEnvInt("RATE_LIMIT_REQUESTS", 100),
+			AuthenticatedRequests: getEnvInt("RATE_LIMIT_AUTHENTICATED_REQUESTS", 300),
+			Burst:                 getEnvInt("RATE_LIMIT_BURST", 20),
+			Window:                time.Duration(getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60)) * time.Second,
+			ChallengeEnabled:      getEnvBool("RATE_LIMIT_CHALLENGE_ENABLED", false),
+			ChallengeTTL:          getEnvDuration("RATE_LIMIT_CHALLENGE_TTL", 10*time.Minute),
 		},
 
 		Redis: RedisConfig{
@@ -101,11 +240,77 @@ func Load() (*Config, error) {
 			AuthURL:    getEnv("AUTH_SERVICE_URL", "http://localhost:8081"),
 			CaptchaURL: getEnv("CAPTCHA_SERVICE_URL", "http://localhost:8082"),
 		},
+
+		TLS: TLSConfig{
+			Enabled:           getEnvBool("TLS_ENABLED", false),
+			CertFile:          getEnv("TLS_CERT_FILE", ""),
+			KeyFile:           getEnv("TLS_KEY_FILE", ""),
+			ClientCAFile:      getEnv("TLS_CLIENT_CA_FILE", ""),
+			RequireClientCert: getEnvBool("TLS_REQUIRE_CLIENT_CERT", false),
+			AllowedCNs:        getEnvSlice("TLS_ALLOWED_CNS", nil),
+			RevokedCNs:        getEnvSlice("TLS_REVOKED_CNS", nil),
+			ClientIdentities:  getEnvClientIdentities("TLS_CLIENT_IDENTITIES"),
+		},
+
+		APIKeyUsage: APIKeyUsageConfig{
+			FlushInterval:  getEnvDuration("API_KEY_USAGE_FLUSH_INTERVAL", 10*time.Second),
+			FlushBatchSize: getEnvInt("API_KEY_USAGE_FLUSH_BATCH_SIZE", 100),
+		},
+
+		Audit: AuditConfig{
+			Enabled:             getEnvBool("AUDIT_ENABLED", true),
+			CaptureRequestBody:  getEnvBool("AUDIT_CAPTURE_REQUEST_BODY", false),
+			CaptureResponseBody: getEnvBool("AUDIT_CAPTURE_RESPONSE_BODY", false),
+			MinStatus:           getEnvInt("AUDIT_CAPTURE_MIN_STATUS", 500),
+			SampleRate:          getEnvFloat("AUDIT_SAMPLE_RATE", 1.0),
+			Sink:                getEnv("AUDIT_SINK", "stdout"),
+			FilePath:            getEnv("AUDIT_FILE_PATH", "audit.log"),
+			FileMaxBytes:        int64(getEnvInt("AUDIT_FILE_MAX_BYTES", 100*1024*1024)),
+			HTTPSinkURL:         getEnv("AUDIT_HTTP_SINK_URL", ""),
+			RedactPattern:       getEnv("AUDIT_REDACT_PATTERN", ""),
+		},
+
+		Telemetry: TelemetryConfig{
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		},
+		Shutdown: ShutdownConfig{
+			PreStopDelay: getEnvDuration("SHUTDOWN_PRE_STOP_DELAY", 5*time.Second),
+			DrainTimeout: getEnvDuration("SHUTDOWN_DRAIN_TIMEOUT", 30*time.Second),
+		},
+
+		UpstreamMTLS: UpstreamMTLSConfig{
+			Enabled:          getEnvBool("UPSTREAM_MTLS_ENABLED", false),
+			CertFile:         getEnv("UPSTREAM_MTLS_CERT_FILE", ""),
+			KeyFile:          getEnv("UPSTREAM_MTLS_KEY_FILE", ""),
+			CACertFile:       getEnv("UPSTREAM_MTLS_CA_FILE", ""),
+			UseSPIFFE:        getEnvBool("UPSTREAM_MTLS_USE_SPIFFE", false),
+			SPIFFESocketPath: getEnv("UPSTREAM_MTLS_SPIFFE_SOCKET", ""),
+		},
+	}
+
+	// Validate required configuration. In RS256 mode the gateway verifies
+	// tokens against the auth service's JWKS instead of a shared secret.
+	if cfg.JWT.SigningMethod != "RS256" {
+		if cfg.JWT.Secret == "" || len(cfg.JWT.Secret) < 32 {
+			return nil, fmt.Errorf("JWT_SECRET must be at least 32 characters")
+		}
+	}
+
+	if cfg.TLS.Enabled && (cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE are required when TLS_ENABLED is true")
 	}
 
-	// Validate required configuration
-	if cfg.JWT.Secret == "" || len(cfg.JWT.Secret) < 32 {
-		return nil, fmt.Errorf("JWT_SECRET must be at least 32 characters")
+	if cfg.Audit.Sink == "http" && cfg.Audit.HTTPSinkURL == "" {
+		return nil, fmt.Errorf("AUDIT_HTTP_SINK_URL is required when AUDIT_SINK is \"http\"")
+	}
+
+	if cfg.UpstreamMTLS.Enabled {
+		if cfg.UpstreamMTLS.CACertFile == "" {
+			return nil, fmt.Errorf("UPSTREAM_MTLS_CA_FILE is required when UPSTREAM_MTLS_ENABLED is true")
+		}
+		if !cfg.UpstreamMTLS.UseSPIFFE && (cfg.UpstreamMTLS.CertFile == "" || cfg.UpstreamMTLS.KeyFile == "") {
+			return nil, fmt.Errorf("UPSTREAM_MTLS_CERT_FILE and UPSTREAM_MTLS_KEY_FILE are required when UPSTREAM_MTLS_ENABLED is true and UPSTREAM_MTLS_USE_SPIFFE is false")
+		}
 	}
 
 	return cfg, nil
@@ -145,6 +350,47 @@ func getEnvSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvClientIdentities parses TLS_CLIENT_IDENTITIES, a comma-separated
+// list of "cn:user_id:scope1|scope2" entries, into a CN-keyed lookup table.
+// A malformed entry is skipped rather than failing startup, since a typo'd
+// mapping should only cost that one CN its identity, not take the gateway
+// down.
+func getEnvClientIdentities(key string) map[string]ClientCertIdentity {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	identities := make(map[string]ClientCertIdentity)
+	for _, entry := range strings.Split(value, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+
+		userID, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		identity := ClientCertIdentity{UserID: userID}
+		if len(fields) >= 3 && fields[2] != "" {
+			identity.Scopes = strings.Split(fields[2], "|")
+		}
+		identities[fields[0]] = identity
+	}
+	return identities
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -152,4 +398,4 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
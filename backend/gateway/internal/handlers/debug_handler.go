@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/captcha-platform/gateway/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+// DebugHandler exposes operational introspection endpoints. Unlike the rest
+// of the gateway's API these aren't meant for callers - mount them behind
+// whatever network policy keeps /metrics operator-only.
+type DebugHandler struct {
+	pool *proxy.Pool
+}
+
+// NewDebugHandler creates a new DebugHandler.
+func NewDebugHandler(pool *proxy.Pool) *DebugHandler {
+	return &DebugHandler{pool: pool}
+}
+
+// certStatus describes one upstream's mTLS client certificate, or why it
+// doesn't have one.
+type certStatus struct {
+	Upstream  string    `json:"upstream"`
+	MTLS      bool      `json:"mtls"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Certs reports the expiry of the mTLS client certificate each upstream
+// proxy is currently presenting, so an operator can catch a stalled
+// rotation before the certificate actually expires.
+func (h *DebugHandler) Certs(c *gin.Context) {
+	statuses := make([]certStatus, 0, len(h.pool.All()))
+	for _, sp := range h.pool.All() {
+		status := certStatus{Upstream: sp.TargetURL()}
+
+		expiry, ok, err := sp.CertExpiry()
+		status.MTLS = ok
+		if err != nil {
+			status.Error = err.Error()
+		} else if ok {
+			status.ExpiresAt = expiry
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"certs": statuses})
+}
@@ -0,0 +1,79 @@
+// Package metrics exports the gateway's Prometheus metrics: request
+// counters and latency histograms labeled by route/status, plus the
+// narrower counters/gauges the proxy, rate limiter, and JWT verifier feed
+// directly (circuit-breaker state, rate-limit rejections, JWT verification
+// failures). Everything here is registered against the default registry and
+// served at /metrics via promhttp.Handler in cmd/main.go.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPRequestsTotal counts every request the gateway has routed,
+	// labeled by method, the matched route template (not the raw path, so
+	// "/api/v1/api-keys/:id" doesn't fragment into one series per ID), and
+	// response status.
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_http_requests_total",
+		Help: "Total number of HTTP requests handled by the gateway.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes request latency, same labels as
+	// HTTPRequestsTotal.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RateLimitRejections counts requests middleware.RateLimit blocked,
+	// labeled by the Policy.Name that rejected them.
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_rate_limit_rejections_total",
+		Help: "Total number of requests rejected by a rate-limit policy.",
+	}, []string{"policy"})
+
+	// CircuitBreakerState mirrors gobreaker.State as a gauge (0=closed,
+	// 1=half-open, 2=open), labeled by upstream target URL, so a dashboard
+	// can alert the instant a breaker trips without scraping logs.
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_circuit_breaker_state",
+		Help: "Circuit breaker state per upstream (0=closed, 1=half-open, 2=open).",
+	}, []string{"upstream"})
+
+	// JWTVerificationFailures counts requests AuthRequired rejected, labeled
+	// missing_header, malformed_header, or invalid_token (any Verifier.Verify
+	// failure). APIKeyOrJWTAuth does not record here.
+	JWTVerificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_jwt_verification_failures_total",
+		Help: "Total number of JWT verification failures, labeled by reason.",
+	}, []string{"reason"})
+)
+
+// Middleware records HTTPRequestsTotal/HTTPRequestDuration for every
+// request. It must run after gin's route matching (i.e. anywhere in the
+// global chain), since it reads c.FullPath() once the handler has returned.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			// No route matched (404) - fall back to the raw path so these
+			// still show up under a stable, if less precise, label.
+			route = c.Request.URL.Path
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		HTTPRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
+		HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
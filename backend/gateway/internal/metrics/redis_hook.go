@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCommandDuration observes go-redis command latency, labeled by
+// command name - see NewRedisMetricsHook.
+var RedisCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gateway_redis_command_duration_seconds",
+	Help:    "Redis command latency in seconds.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"command"})
+
+// redisMetricsHook observes RedisCommandDuration for every command run
+// through a client it's attached to via Client.AddHook.
+type redisMetricsHook struct{}
+
+// NewRedisMetricsHook creates a redis.Hook that records RedisCommandDuration
+// per command name. Attach it to a client with client.AddHook(...) right
+// after construction, before the client sees any traffic.
+func NewRedisMetricsHook() redis.Hook {
+	return redisMetricsHook{}
+}
+
+func (redisMetricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisMetricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		RedisCommandDuration.WithLabelValues(cmd.Name()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (redisMetricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start).Seconds()
+		for _, cmd := range cmds {
+			RedisCommandDuration.WithLabelValues(cmd.Name()).Observe(elapsed)
+		}
+		return err
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/captcha-platform/gateway/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// auditBodyCaptureLimit bounds how much of a request/response body
+// AuditLog buffers for the audit trail - a multi-megabyte captcha
+// image upload has no business sitting in an audit log.
+const auditBodyCaptureLimit = 64 * 1024
+
+// CaptureConfig controls how much of a request AuditLog captures beyond
+// the always-recorded method/path/status/latency/identity - whether
+// bodies are captured at all, only on certain status classes (e.g. only
+// 5xx, to keep routine 2xx traffic cheap to store), and at what sample
+// rate.
+type CaptureConfig struct {
+	CaptureRequestBody  bool
+	CaptureResponseBody bool
+
+	// MinStatus, when set, restricts body capture to responses with this
+	// status or higher - e.g. 500 to only capture bodies on server errors.
+	// 0 means capture regardless of status.
+	MinStatus int
+
+	// SampleRate is the fraction (0..1) of eligible requests that get body
+	// capture; headers/status/latency/identity are always recorded
+	// regardless. 0 or >=1 mean "don't sample" (never / always capture).
+	SampleRate float64
+}
+
+// shouldCaptureBody reports whether this request, now that its status is
+// known, qualifies for body capture under cfg.
+func (cfg CaptureConfig) shouldCaptureBody(status int) bool {
+	if cfg.MinStatus > 0 && status < cfg.MinStatus {
+		return false
+	}
+	if cfg.SampleRate <= 0 {
+		return false
+	}
+	if cfg.SampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < cfg.SampleRate
+}
+
+// auditResponseWriter wraps gin.ResponseWriter to mirror the response body
+// into a size-capped buffer as it's written, without buffering the whole
+// thing if it's large - only the first auditBodyCaptureLimit bytes are
+// kept.
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.buf.Len() < w.limit {
+		remaining := w.limit - w.buf.Len()
+		if remaining > len(b) {
+			remaining = len(b)
+		}
+		w.buf.Write(b[:remaining])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// AuditLog returns a middleware that records one audit.Record per request
+// via auditLogger, covering method/path/status/latency/caller identity
+// unconditionally and request/response headers/bodies subject to cfg and
+// redactor. proxy.ServiceProxy.buildRequest stashes the upstream it routed
+// to under the "audit_upstream" context key, which this reads back after
+// c.Next() to include in the record.
+func AuditLog(auditLogger *audit.Logger, redactor *audit.Redactor, cfg CaptureConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var reqBody []byte
+		if cfg.CaptureRequestBody && c.Request.Body != nil {
+			limited := io.LimitReader(c.Request.Body, auditBodyCaptureLimit+1)
+			reqBody, _ = io.ReadAll(limited)
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(reqBody), c.Request.Body))
+		}
+
+		var respWriter *auditResponseWriter
+		if cfg.CaptureResponseBody {
+			respWriter = &auditResponseWriter{ResponseWriter: c.Writer, limit: auditBodyCaptureLimit}
+			c.Writer = respWriter
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		rec := audit.Record{
+			Timestamp: start.UTC(),
+			RequestID: c.GetString("request_id"),
+			TraceID:   c.GetHeader("X-Trace-ID"),
+			SpanID:    c.GetHeader("X-Span-ID"),
+
+			Method:   c.Request.Method,
+			Path:     c.Request.URL.Path,
+			Query:    c.Request.URL.RawQuery,
+			Upstream: c.GetString("audit_upstream"),
+
+			Status:    status,
+			LatencyMS: time.Since(start).Milliseconds(),
+
+			ClientIP: c.ClientIP(),
+			AuthType: c.GetString("auth_type"),
+		}
+		if userID, exists := c.Get("user_id"); exists {
+			rec.UserID = uintToString(userID.(uint64))
+		}
+		if apiKeyHash, exists := c.Get("api_key_hash"); exists {
+			rec.APIKeyHash = apiKeyHash.(string)
+		}
+
+		rec.RequestHeaders = redactor.Headers(c.Request.Header)
+		rec.ResponseHeaders = redactor.Headers(c.Writer.Header())
+
+		if cfg.shouldCaptureBody(status) {
+			if len(reqBody) > 0 {
+				rec.RequestBody = redactor.Body(truncate(reqBody, auditBodyCaptureLimit))
+			}
+			if respWriter != nil && respWriter.buf.Len() > 0 {
+				rec.ResponseBody = redactor.Body(truncate(respWriter.buf.Bytes(), auditBodyCaptureLimit))
+			}
+		}
+
+		auditLogger.Log(rec)
+	}
+}
+
+func truncate(b []byte, limit int) []byte {
+	if len(b) > limit {
+		return b[:limit]
+	}
+	return b
+}
@@ -1,21 +1,28 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/captcha-platform/gateway/internal/config"
+	"github.com/captcha-platform/gateway/internal/metrics"
+	"github.com/captcha-platform/gateway/pkg/jwks"
 	"github.com/captcha-platform/gateway/pkg/logger"
+	"github.com/captcha-platform/gateway/pkg/scopes"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger returns a middleware that logs requests
@@ -44,12 +51,21 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 	}
 }
 
-// RequestID adds a unique request ID to each request
+// RequestID adds a unique request ID to each request. When otelgin has
+// already started a span for this request (it must run ahead of this
+// middleware in the chain), its trace ID is adopted as the request ID
+// instead of a fresh UUID, so a log line and its trace are the same
+// identifier - no separate correlation step needed to go from one to the
+// other.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
 		if requestID == "" {
-			requestID = uuid.New().String()
+			if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.HasTraceID() {
+				requestID = spanCtx.TraceID().String()
+			} else {
+				requestID = uuid.New().String()
+			}
 		}
 
 		c.Set("request_id", requestID)
@@ -109,12 +125,14 @@ func SecurityHeaders() gin.HandlerFunc {
 	}
 }
 
-// AuthRequired returns a middleware that requires JWT authentication
-func AuthRequired(secret string) gin.HandlerFunc {
+// AuthRequired returns a middleware that requires JWT authentication,
+// verified by verifier.
+func AuthRequired(verifier *Verifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
+			metrics.JWTVerificationFailures.WithLabelValues("missing_header").Inc()
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
 				"message": "Authorization header is required",
@@ -125,6 +143,7 @@ func AuthRequired(secret string) gin.HandlerFunc {
 		// Check Bearer prefix
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			metrics.JWTVerificationFailures.WithLabelValues("malformed_header").Inc()
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
 				"message": "Invalid authorization header format",
@@ -134,9 +153,9 @@ func AuthRequired(secret string) gin.HandlerFunc {
 
 		token := parts[1]
 
-		// Validate token using internal JWT validation
-		claims, err := validateJWT(token, secret)
+		claims, err := verifier.Verify(c.Request.Context(), token)
 		if err != nil {
+			metrics.JWTVerificationFailures.WithLabelValues("invalid_token").Inc()
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
 				"message": "Invalid or expired token",
@@ -148,6 +167,7 @@ func AuthRequired(secret string) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("auth_type", "jwt")
 
 		// Forward token to downstream services
 		c.Request.Header.Set("X-User-ID", uintToString(claims.UserID))
@@ -158,13 +178,229 @@ func AuthRequired(secret string) gin.HandlerFunc {
 	}
 }
 
-// APIKeyOrJWTAuth allows authentication via API key or JWT
-func APIKeyOrJWTAuth(jwtSecret string) gin.HandlerFunc {
+// APIKeyIntrospector resolves a raw API key to its scopes and per-key rate
+// limit by asking the auth service, which owns the api_keys table. The
+// gateway has no database of its own, so this is a synchronous call on
+// every API-key request - fine for now, but the natural place to add a
+// cache if introspection latency ever becomes a problem.
+type APIKeyIntrospector struct {
+	authURL    string
+	httpClient *http.Client
+}
+
+// NewAPIKeyIntrospector creates an APIKeyIntrospector targeting the auth
+// service at authURL.
+func NewAPIKeyIntrospector(authURL string) *APIKeyIntrospector {
+	return &APIKeyIntrospector{
+		authURL:    strings.TrimRight(authURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type apiKeyIntrospection struct {
+	Valid     bool     `json:"valid"`
+	UserID    uint64   `json:"user_id"`
+	KeyID     uint64   `json:"key_id"`
+	KeyPrefix string   `json:"key_prefix"`
+	Scopes    []string `json:"scopes"`
+	RateLimit int      `json:"rate_limit"`
+}
+
+// introspect asks the auth service to validate apiKey and return its
+// scopes and rate limit.
+func (i *APIKeyIntrospector) introspect(ctx context.Context, apiKey string) (*apiKeyIntrospection, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.authURL+"/api/v1/internal/api-keys/introspect", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-Key", apiKey)
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result apiKeyIntrospection
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// UsageRecorder batches API-key usage (one increment per authenticated
+// request) and periodically reports aggregated per-key counts to the auth
+// service, instead of writing total_requests/last_used_at on every request.
+// Increments are buffered on a channel and flushed by a single background
+// goroutine whenever flushInterval elapses or batchSize increments have
+// queued, whichever comes first.
+type UsageRecorder struct {
+	authURL    string
+	httpClient *http.Client
+	batchSize  int
+	increments chan uint64
+	done       chan struct{}
+}
+
+// NewUsageRecorder creates a UsageRecorder that reports to the auth service
+// at authURL, flushing at least every flushInterval or every batchSize
+// increments.
+func NewUsageRecorder(authURL string, flushInterval time.Duration, batchSize int) *UsageRecorder {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	r := &UsageRecorder{
+		authURL:    strings.TrimRight(authURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		batchSize:  batchSize,
+		increments: make(chan uint64, batchSize*4),
+		done:       make(chan struct{}),
+	}
+
+	go r.run(flushInterval)
+
+	return r
+}
+
+// Record queues a usage increment for keyID. It never blocks the request
+// path - if the buffer is full, the increment is dropped and picked up on
+// the caller's next request instead.
+func (r *UsageRecorder) Record(keyID uint64) {
+	select {
+	case r.increments <- keyID:
+	default:
+	}
+}
+
+// Stop tells the background goroutine to flush every buffered increment and
+// exit. It returns without waiting for that flush to complete.
+func (r *UsageRecorder) Stop() {
+	close(r.done)
+}
+
+func (r *UsageRecorder) run(flushInterval time.Duration) {
+	if flushInterval <= 0 {
+		flushInterval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	counts := make(map[uint64]int)
+	buffered := 0
+
+	flush := func() {
+		if len(counts) == 0 {
+			return
+		}
+		r.flush(counts)
+		counts = make(map[uint64]int)
+		buffered = 0
+	}
+
+	for {
+		select {
+		case keyID := <-r.increments:
+			counts[keyID]++
+			buffered++
+			if buffered >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.done:
+			for {
+				select {
+				case keyID := <-r.increments:
+					counts[keyID]++
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+type apiKeyUsageIncrement struct {
+	KeyID uint64 `json:"key_id"`
+	Count int    `json:"count"`
+}
+
+func (r *UsageRecorder) flush(counts map[uint64]int) {
+	events := make([]apiKeyUsageIncrement, 0, len(counts))
+	for keyID, count := range counts {
+		events = append(events, apiKeyUsageIncrement{KeyID: keyID, Count: count})
+	}
+
+	body, err := json.Marshal(events)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.authURL+"/api/v1/internal/api-keys/usage", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// APIKeyOrJWTAuth allows authentication via mTLS client certificate, API
+// key, or JWT. A nil introspector disables API-key validation at the
+// gateway, falling back to the old forward-and-trust-downstream behavior.
+// A nil recorder simply skips usage recording. JWTs are verified by
+// verifier, same as AuthRequired. tlsCfg supplies the mTLS CN allow-list,
+// revocation list, and CN-to-identity mapping.
+func APIKeyOrJWTAuth(verifier *Verifier, introspector *APIKeyIntrospector, recorder *UsageRecorder, tlsCfg config.TLSConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// mTLS client certificate takes priority when present - the TLS handshake
+		// already proved possession of the private key, so there's no credential
+		// to steal or forward like there is with an API key or bearer token.
+		if cn, ok := clientCertCommonName(c); ok {
+			if len(tlsCfg.AllowedCNs) > 0 && !containsString(tlsCfg.AllowedCNs, cn) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error":   "cert_not_allowed",
+					"message": "Client certificate CN is not authorized",
+				})
+				return
+			}
+			if containsString(tlsCfg.RevokedCNs, cn) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "cert_revoked",
+					"message": "Client certificate has been revoked",
+				})
+				return
+			}
+
+			c.Set("client_cert_cn", cn)
+			c.Set("auth_type", "mtls")
+			c.Request.Header.Set("X-Client-Cert-CN", cn)
+
+			if identity, ok := tlsCfg.ClientIdentities[cn]; ok {
+				c.Set("user_id", identity.UserID)
+				c.Request.Header.Set("X-User-ID", uintToString(identity.UserID))
+
+				if len(identity.Scopes) > 0 {
+					c.Set("api_key_scopes", identity.Scopes)
+					c.Request.Header.Set("X-User-Scopes", strings.Join(identity.Scopes, ","))
+				}
+			}
+
+			c.Next()
+			return
+		}
+
 		// Check for API key first
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey != "" {
-			// Hash the API key to validate
 			keyHash := sha256.Sum256([]byte(apiKey))
 			c.Set("api_key_hash", hex.EncodeToString(keyHash[:]))
 			c.Set("auth_type", "api_key")
@@ -172,6 +408,31 @@ func APIKeyOrJWTAuth(jwtSecret string) gin.HandlerFunc {
 			// Forward the API key hash to downstream service for validation
 			c.Request.Header.Set("X-API-Key-Hash", hex.EncodeToString(keyHash[:]))
 
+			if introspector != nil {
+				result, err := introspector.introspect(c.Request.Context(), apiKey)
+				if err != nil || !result.Valid {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+						"error":   "unauthorized",
+						"message": "Invalid API key",
+					})
+					return
+				}
+
+				c.Set("api_key_scopes", result.Scopes)
+				c.Set("api_key_rate_limit", result.RateLimit)
+				c.Set("api_key_prefix", result.KeyPrefix)
+
+				// Forward the resolved scopes so upstream services can re-check
+				// RequireScope-style without another introspection round trip.
+				if len(result.Scopes) > 0 {
+					c.Request.Header.Set("X-User-Scopes", strings.Join(result.Scopes, ","))
+				}
+
+				if recorder != nil {
+					recorder.Record(result.KeyID)
+				}
+			}
+
 			c.Next()
 			return
 		}
@@ -182,7 +443,7 @@ func APIKeyOrJWTAuth(jwtSecret string) gin.HandlerFunc {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
 				token := parts[1]
-				claims, err := validateJWT(token, jwtSecret)
+				claims, err := verifier.Verify(c.Request.Context(), token)
 				if err == nil {
 					c.Set("user_id", claims.UserID)
 					c.Set("user_email", claims.Email)
@@ -201,62 +462,30 @@ func APIKeyOrJWTAuth(jwtSecret string) gin.HandlerFunc {
 
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"error":   "unauthorized",
-			"message": "API key or valid JWT token required",
+			"message": "A client certificate, API key, or valid JWT token is required",
 		})
 	}
 }
 
-// NewRedisClient creates a new Redis client
-func NewRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr(),
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, err
-	}
-
-	return client, nil
-}
-
-// RateLimiterRedis returns a Redis-based rate limiter middleware
-func RateLimiterRedis(client *redis.Client, maxRequests int, window time.Duration) gin.HandlerFunc {
+// RequireScope 403s an API-key or mTLS caller whose granted scopes
+// (resolved by APIKeyOrJWTAuth from the introspector or TLSConfig.
+// ClientIdentities, respectively) don't cover required. JWT callers are
+// governed by their own role checks upstream and pass through untouched.
+func RequireScope(required string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx := c.Request.Context()
-		key := "rate_limit:" + c.ClientIP()
-
-		// Increment and get current count
-		pipe := client.Pipeline()
-		incr := pipe.Incr(ctx, key)
-		pipe.Expire(ctx, key, window)
-		_, err := pipe.Exec(ctx)
-
-		if err != nil {
-			// If Redis fails, allow the request
+		authType, _ := c.Get("auth_type")
+		if authType != "api_key" && authType != "mtls" {
 			c.Next()
 			return
 		}
 
-		count := incr.Val()
-		remaining := maxRequests - int(count)
-		if remaining < 0 {
-			remaining = 0
-		}
+		granted, _ := c.Get("api_key_scopes")
+		grantedScopes, _ := granted.([]string)
 
-		// Set rate limit headers
-		c.Header("X-RateLimit-Limit", intToString(maxRequests))
-		c.Header("X-RateLimit-Remaining", intToString(remaining))
-		c.Header("X-RateLimit-Reset", intToString(int(window.Seconds())))
-
-		if count > int64(maxRequests) {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error":   "rate_limit_exceeded",
-				"message": "Too many requests, please try again later",
+		if !scopes.AnyGrants(grantedScopes, required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "insufficient_scope",
+				"message": "API key is missing required scope: " + required,
 			})
 			return
 		}
@@ -265,69 +494,69 @@ func RateLimiterRedis(client *redis.Client, maxRequests int, window time.Duratio
 	}
 }
 
-// RateLimiterMemory returns an in-memory rate limiter middleware
-func RateLimiterMemory(maxRequests int) gin.HandlerFunc {
-	type client struct {
-		count    int
-		lastSeen time.Time
-	}
+// RequireAnyScope is RequireScope for routes that accept any one of several
+// scopes (e.g. a read endpoint usable with either "stats:read" or
+// "admin:*"), instead of demanding all of them.
+func RequireAnyScope(required ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authType, _ := c.Get("auth_type")
+		if authType != "api_key" && authType != "mtls" {
+			c.Next()
+			return
+		}
 
-	var mu sync.Mutex
-	clients := make(map[string]*client)
-	window := time.Minute
+		granted, _ := c.Get("api_key_scopes")
+		grantedScopes, _ := granted.([]string)
 
-	// Cleanup goroutine
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, cl := range clients {
-				if time.Since(cl.lastSeen) > window*2 {
-					delete(clients, ip)
-				}
+		for _, req := range required {
+			if scopes.AnyGrants(grantedScopes, req) {
+				c.Next()
+				return
 			}
-			mu.Unlock()
 		}
-	}()
 
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		now := time.Now()
-
-		mu.Lock()
-		if cl, exists := clients[ip]; exists {
-			if now.Sub(cl.lastSeen) > window {
-				cl.count = 1
-				cl.lastSeen = now
-			} else {
-				cl.count++
-			}
-
-			remaining := maxRequests - cl.count
-			if remaining < 0 {
-				remaining = 0
-			}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":   "insufficient_scope",
+			"message": "API key is missing one of the required scopes: " + strings.Join(required, ", "),
+		})
+	}
+}
 
-			c.Header("X-RateLimit-Limit", intToString(maxRequests))
-			c.Header("X-RateLimit-Remaining", intToString(remaining))
+// clientCertCommonName extracts the verified client certificate's subject CN,
+// if mTLS was negotiated for this connection.
+func clientCertCommonName(c *gin.Context) (string, bool) {
+	if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+		return "", false
+	}
+	return c.Request.TLS.PeerCertificates[0].Subject.CommonName, true
+}
 
-			if cl.count > maxRequests {
-				mu.Unlock()
-				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-					"error":   "rate_limit_exceeded",
-					"message": "Too many requests, please try again later",
-				})
-				return
-			}
-		} else {
-			clients[ip] = &client{count: 1, lastSeen: now}
-			c.Header("X-RateLimit-Limit", intToString(maxRequests))
-			c.Header("X-RateLimit-Remaining", intToString(maxRequests-1))
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
 		}
-		mu.Unlock()
+	}
+	return false
+}
 
-		c.Next()
+// NewRedisClient creates a new Redis client
+func NewRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	client.AddHook(metrics.NewRedisMetricsHook())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
 	}
+
+	return client, nil
 }
 
 // JWT Claims structure
@@ -337,30 +566,122 @@ type JWTClaims struct {
 	Role   string
 }
 
-// Simple JWT validation (in production, use proper JWT library)
-func validateJWT(tokenString, secret string) (*JWTClaims, error) {
-	// This is a simplified version - the actual implementation would use
-	// the golang-jwt library. For now, we'll forward the token to auth service
-	// which will do the actual validation.
+// accessTokenClaims mirrors the auth service's AccessClaims shape (see the
+// auth service's pkg/jwt.AccessClaims).
+type accessTokenClaims struct {
+	UserID uint64 `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// Verifier validates bearer access tokens for AuthRequired and
+// APIKeyOrJWTAuth. When jwksClient is non-nil, RS256/ES256 tokens are
+// verified against the auth service's published keys, selected by the
+// token's "kid" header; otherwise only the shared HS256 secret is accepted.
+// issuer and audience, when set, are checked against the token's iss/aud
+// claims. When redisClient is non-nil, a token is also rejected if its jti
+// was individually revoked or it was issued before its user's last
+// revoke-all - the same Redis keys the auth service's jwt.Validator
+// maintains, so a /logout there takes effect here too.
+type Verifier struct {
+	jwksClient *jwks.Client
+	secret     string
+	issuer     string
+	audience   string
+	redis      *redis.Client
+}
+
+// NewVerifier creates a Verifier. jwksClient, audience, and redisClient may
+// all be nil/empty, in which case the corresponding check is skipped.
+func NewVerifier(jwksClient *jwks.Client, secret, issuer, audience string, redisClient *redis.Client) *Verifier {
+	return &Verifier{
+		jwksClient: jwksClient,
+		secret:     secret,
+		issuer:     issuer,
+		audience:   audience,
+		redis:      redisClient,
+	}
+}
+
+// Verify validates a bearer token's signature, expiry, iss/aud, and
+// revocation status, returning the caller's identity from its claims.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	claims := &accessTokenClaims{}
 
-	// Parse token manually (simplified for gateway)
-	parts := strings.Split(tokenString, ".")
-	if len(parts) != 3 {
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+			if v.jwksClient == nil {
+				return nil, ErrInvalidToken
+			}
+			kid, _ := t.Header["kid"].(string)
+			return v.jwksClient.PublicKey(kid)
+		case *jwt.SigningMethodHMAC:
+			if v.jwksClient != nil {
+				// JWKS verification is configured for this deployment; don't
+				// also accept an HS256-signed token.
+				return nil, ErrInvalidToken
+			}
+			return []byte(v.secret), nil
+		default:
+			return nil, ErrInvalidToken
+		}
+	}
+
+	var opts []jwt.ParserOption
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, opts...)
+	if err != nil || !token.Valid {
 		return nil, ErrInvalidToken
 	}
 
-	// In a real implementation, decode and verify the signature here
-	// For now, just extract basic claims and forward to auth service
+	if revoked, err := v.isRevoked(ctx, claims); err != nil || revoked {
+		return nil, ErrInvalidToken
+	}
 
-	// This is a placeholder - the actual validation happens in auth service
-	// Gateway just checks format and forwards
 	return &JWTClaims{
-		UserID: 0,
-		Email:  "",
-		Role:   "",
+		UserID: claims.UserID,
+		Email:  claims.Email,
+		Role:   claims.Role,
 	}, nil
 }
 
+// isRevoked reports whether claims identify an access token that was
+// individually revoked or was issued before its user's last revoke-all, per
+// the keys the auth service's jwt.Validator writes on logout. Both checks
+// are pipelined into a single Redis round trip.
+func (v *Verifier) isRevoked(ctx context.Context, claims *accessTokenClaims) (bool, error) {
+	if v.redis == nil {
+		return false, nil
+	}
+
+	pipe := v.redis.Pipeline()
+	jtiCmd := pipe.Exists(ctx, "revoked_jti:"+claims.ID)
+	notBeforeCmd := pipe.Get(ctx, fmt.Sprintf("user_not_before:%d", claims.UserID))
+	pipe.Exec(ctx)
+
+	if n, err := jtiCmd.Result(); err == nil && n > 0 {
+		return true, nil
+	}
+
+	if notBeforeStr, err := notBeforeCmd.Result(); err == nil {
+		if notBefore, parseErr := time.Parse(time.RFC3339Nano, notBeforeStr); parseErr == nil {
+			if claims.IssuedAt != nil && claims.IssuedAt.Time.Before(notBefore) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
 // Error types
 type TokenError struct {
 	Message string
@@ -379,4 +700,4 @@ func uintToString(n uint64) string {
 
 func intToString(n int) string {
 	return strconv.Itoa(n)
-}
\ No newline at end of file
+}
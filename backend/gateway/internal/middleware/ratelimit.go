@@ -0,0 +1,420 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/captcha-platform/gateway/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// Quota is the limit enforced for whatever identity a Policy's key
+// extractor resolves per-caller: Limit requests per Window, with Burst
+// allowed to pass through in a single instant before smoothing kicks in. A
+// zero Limit means "unlimited" - RateLimit skips enforcement entirely
+// rather than asking a Limiter to divide by zero.
+type Quota struct {
+	Limit  int
+	Burst  int
+	Window time.Duration
+}
+
+// Decision is what a Limiter reports back for a single Allow call.
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // only meaningful when !Allowed
+}
+
+// Limiter decides whether the caller identified by key may proceed under
+// quota. Implementations must be safe for concurrent use.
+type Limiter interface {
+	Allow(ctx context.Context, key string, quota Quota) (Decision, error)
+}
+
+// KeyExtractor resolves the identity a Policy rate-limits on from the
+// request - the client IP, an API-key hash, a user_id claim, or a custom
+// header.
+type KeyExtractor func(c *gin.Context) string
+
+// ExtractClientIP rate-limits per client IP.
+func ExtractClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ExtractAPIKeyHash rate-limits per API key, falling back to the client IP
+// for callers APIKeyOrJWTAuth didn't authenticate via an API key.
+func ExtractAPIKeyHash(c *gin.Context) string {
+	if hash := c.GetString("api_key_hash"); hash != "" {
+		return hash
+	}
+	return c.ClientIP()
+}
+
+// ExtractUserID rate-limits per authenticated user, falling back to the
+// client IP for callers with no user_id in context.
+func ExtractUserID(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("%v", userID)
+	}
+	return c.ClientIP()
+}
+
+// ExtractHeader rate-limits per value of the named header, falling back to
+// the client IP when the header is absent.
+func ExtractHeader(name string) KeyExtractor {
+	return func(c *gin.Context) string {
+		if v := c.GetHeader(name); v != "" {
+			return v
+		}
+		return c.ClientIP()
+	}
+}
+
+// ChallengeConfig lets a Policy offer a challenge token instead of a hard
+// 429 to a blocked caller, so a borderline offender can prove they're human
+// via the captcha service's /solve endpoint instead of being shut out
+// outright.
+type ChallengeConfig struct {
+	Enabled bool
+	TTL     time.Duration // how long the issued token remains valid; defaults to 10m
+}
+
+// Policy declares a rate limit for one route or route group: how the caller
+// is identified, its anonymous and authenticated quotas, and whether
+// blocked callers get a challenge instead of a 429.
+type Policy struct {
+	// Name namespaces this policy's keys so two policies sharing an
+	// Extractor (e.g. both keyed by client IP) don't share a counter.
+	Name      string
+	Extractor KeyExtractor
+
+	Anonymous     Quota
+	Authenticated Quota // zero Limit means "same as Anonymous"
+
+	// LimitContextKey, if set, overrides the resolved quota's Limit with an
+	// int stashed in the gin context under this key (e.g. "api_key_rate_limit",
+	// set by APIKeyOrJWTAuth from the per-key limit the auth service
+	// returned) - Burst and Window still come from the matched quota.
+	LimitContextKey string
+
+	Challenge ChallengeConfig
+}
+
+// quota resolves the Quota that applies to this request: Authenticated for
+// a caller AuthRequired/APIKeyOrJWTAuth has identified (unless the policy
+// didn't configure one), Anonymous otherwise, with LimitContextKey able to
+// override the Limit either way.
+func (p Policy) quota(c *gin.Context) Quota {
+	q := p.Anonymous
+	if p.Authenticated.Limit > 0 && isAuthenticated(c) {
+		q = p.Authenticated
+	}
+
+	if p.LimitContextKey != "" {
+		if v, exists := c.Get(p.LimitContextKey); exists {
+			if limit, ok := v.(int); ok && limit > 0 {
+				q.Limit = limit
+			}
+		}
+	}
+
+	return q
+}
+
+func isAuthenticated(c *gin.Context) bool {
+	authType, exists := c.Get("auth_type")
+	return exists && authType != ""
+}
+
+// RateLimit enforces policy against limiter, extracting the caller's key via
+// policy.Extractor and applying the caller's anonymous or authenticated
+// quota. Every response carries the draft-ietf-httpapi-ratelimit-headers
+// RateLimit-Limit/Remaining/Reset headers; a blocked request also gets
+// Retry-After and either a 429, or - when policy.Challenge is enabled - a
+// 403 carrying a challenge token in place of the hard block. A Limiter
+// error (e.g. Redis unreachable) fails open rather than blocking traffic.
+func RateLimit(limiter Limiter, challenges ChallengeStore, policy Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		quota := policy.quota(c)
+		if quota.Limit <= 0 {
+			c.Next()
+			return
+		}
+
+		key := policy.Name + ":" + policy.Extractor(c)
+
+		decision, err := limiter.Allow(c.Request.Context(), key, quota)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", intToString(decision.Limit))
+		c.Header("RateLimit-Remaining", intToString(decision.Remaining))
+		c.Header("RateLimit-Reset", intToString(int(quota.Window.Seconds())))
+
+		if decision.Allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(decision.RetryAfter.Seconds())
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", intToString(retryAfter))
+
+		metrics.RateLimitRejections.WithLabelValues(policy.Name).Inc()
+
+		if policy.Challenge.Enabled && challenges != nil {
+			if token, err := challenges.Issue(c.Request.Context(), key, policy.Challenge.TTL); err == nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error":           "challenge_required",
+					"message":         "Rate limit exceeded; solve the returned challenge to continue",
+					"challenge_token": token,
+				})
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":   "rate_limit_exceeded",
+			"message": "Too many requests, please try again later",
+		})
+	}
+}
+
+// TokenBucketLimiter is an in-process Limiter sharded by key, backed by
+// golang.org/x/time/rate. It's the fallback when no Redis is configured -
+// each gateway instance enforces its own independent bucket per key, same
+// tradeoff the old RateLimiterMemory made, but with real smoothing and
+// burst support instead of a fixed one-minute window.
+type TokenBucketLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	limiter  *rate.Limiter
+	limit    int
+	burst    int
+	lastSeen time.Time
+}
+
+// NewTokenBucketLimiter creates a TokenBucketLimiter and starts its
+// background goroutine that evicts buckets idle for more than ten minutes.
+func NewTokenBucketLimiter() *TokenBucketLimiter {
+	l := &TokenBucketLimiter{buckets: make(map[string]*tokenBucket)}
+	go l.evictExpired()
+	return l
+}
+
+func (l *TokenBucketLimiter) evictExpired() {
+	for {
+		time.Sleep(time.Minute)
+		l.mu.Lock()
+		for key, b := range l.buckets {
+			if time.Since(b.lastSeen) > 10*time.Minute {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Allow implements Limiter.
+func (l *TokenBucketLimiter) Allow(_ context.Context, key string, quota Quota) (Decision, error) {
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok || b.limit != quota.Limit || b.burst != quota.Burst {
+		// No existing bucket, or this key's quota changed since it was
+		// created (e.g. a per-API-key limit the operator just rotated) -
+		// rate.Limiter bakes its rate/burst in at construction, so either
+		// case needs a fresh one.
+		b = &tokenBucket{
+			limiter: rate.NewLimiter(rate.Every(quota.Window/time.Duration(quota.Limit)), quota.Burst),
+			limit:   quota.Limit,
+			burst:   quota.Burst,
+		}
+		l.buckets[key] = b
+	}
+	b.lastSeen = time.Now()
+	bucketLimiter := b.limiter
+	l.mu.Unlock()
+
+	reservation := bucketLimiter.Reserve()
+	if !reservation.OK() {
+		return Decision{Allowed: false, Limit: quota.Limit}, nil
+	}
+
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return Decision{Allowed: false, Limit: quota.Limit, RetryAfter: delay}, nil
+	}
+
+	remaining := int(bucketLimiter.Tokens())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Decision{Allowed: true, Limit: quota.Limit, Remaining: remaining}, nil
+}
+
+// slidingWindowScript atomically expires entries older than the window,
+// records this request, and reports the resulting count plus how long until
+// the oldest entry in the window ages out - the retry-after a caller over
+// quota should wait. Doing this as one script avoids the classic
+// INCR-then-EXPIRE race, where the EXPIRE only takes effect on the first
+// hit of a window and a crash or contention between the two commands leaves
+// the key permanent.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now_ms = tonumber(ARGV[1])
+local window_ms = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local member = ARGV[4]
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now_ms - window_ms)
+redis.call('ZADD', key, now_ms, member)
+local count = redis.call('ZCARD', key)
+redis.call('PEXPIRE', key, window_ms)
+
+local retry_after_ms = 0
+if count > limit then
+	local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+	if oldest[2] then
+		retry_after_ms = (tonumber(oldest[2]) + window_ms) - now_ms
+		if retry_after_ms < 0 then
+			retry_after_ms = 0
+		end
+	end
+end
+
+return {count, retry_after_ms}
+`)
+
+// RedisSlidingWindowLimiter is a Redis-backed Limiter implementing a
+// sliding-window log per key (a ZSET of request timestamps), shared across
+// every gateway instance. Unlike TokenBucketLimiter it enforces the same
+// quota cluster-wide instead of per-process. It does not use Quota.Burst:
+// up to Limit requests may pass in a single instant.
+type RedisSlidingWindowLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisSlidingWindowLimiter creates a RedisSlidingWindowLimiter using client.
+func NewRedisSlidingWindowLimiter(client *redis.Client) *RedisSlidingWindowLimiter {
+	return &RedisSlidingWindowLimiter{client: client}
+}
+
+// Allow implements Limiter.
+func (l *RedisSlidingWindowLimiter) Allow(ctx context.Context, key string, quota Quota) (Decision, error) {
+	now := time.Now()
+	member := fmt.Sprintf("%d-%s", now.UnixNano(), uuid.New().String())
+
+	res, err := slidingWindowScript.Run(ctx, l.client, []string{"ratelimit:" + key},
+		now.UnixMilli(), quota.Window.Milliseconds(), quota.Limit, member).Result()
+	if err != nil {
+		return Decision{}, err
+	}
+
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return Decision{}, fmt.Errorf("ratelimit: unexpected script result %v", res)
+	}
+	count, _ := vals[0].(int64)
+	retryAfterMs, _ := vals[1].(int64)
+
+	remaining := quota.Limit - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Decision{
+		Allowed:    count <= int64(quota.Limit),
+		Limit:      quota.Limit,
+		Remaining:  remaining,
+		RetryAfter: time.Duration(retryAfterMs) * time.Millisecond,
+	}, nil
+}
+
+// ChallengeStore issues the short-lived tokens RateLimit hands a blocked
+// caller under a Policy with Challenge.Enabled, in place of a hard 429.
+type ChallengeStore interface {
+	Issue(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+const defaultChallengeTTL = 10 * time.Minute
+
+// RedisChallengeStore issues challenge tokens backed by a Redis key per
+// token, so any gateway instance can be the one to eventually see it
+// redeemed.
+type RedisChallengeStore struct {
+	client *redis.Client
+}
+
+// NewRedisChallengeStore creates a RedisChallengeStore using client.
+func NewRedisChallengeStore(client *redis.Client) *RedisChallengeStore {
+	return &RedisChallengeStore{client: client}
+}
+
+// Issue implements ChallengeStore.
+func (s *RedisChallengeStore) Issue(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = defaultChallengeTTL
+	}
+	token := uuid.New().String()
+	if err := s.client.Set(ctx, "ratelimit:challenge:"+token, key, ttl).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// InMemoryChallengeStore is the no-Redis fallback for ChallengeStore. Like
+// TokenBucketLimiter, it only tracks tokens issued by this process.
+type InMemoryChallengeStore struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time // token -> expiry
+}
+
+// NewInMemoryChallengeStore creates an InMemoryChallengeStore and starts its
+// background goroutine that evicts expired tokens.
+func NewInMemoryChallengeStore() *InMemoryChallengeStore {
+	s := &InMemoryChallengeStore{tokens: make(map[string]time.Time)}
+	go s.evictExpired()
+	return s
+}
+
+func (s *InMemoryChallengeStore) evictExpired() {
+	for {
+		time.Sleep(time.Minute)
+		now := time.Now()
+		s.mu.Lock()
+		for token, expiresAt := range s.tokens {
+			if now.After(expiresAt) {
+				delete(s.tokens, token)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Issue implements ChallengeStore.
+func (s *InMemoryChallengeStore) Issue(_ context.Context, _ string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = defaultChallengeTTL
+	}
+	token := uuid.New().String()
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(ttl)
+	s.mu.Unlock()
+	return token, nil
+}
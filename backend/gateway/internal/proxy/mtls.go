@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/captcha-platform/gateway/pkg/logger"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spiffe/go-spiffe/v2/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+)
+
+// MTLSConfig controls whether ServiceProxy dials upstreams over mutual TLS,
+// and where it gets its client identity from. Plain HTTP (the zero value) is
+// still the default - the gateway's X-User-* identity headers are only as
+// trustworthy as the transport carrying them, so this closes that gap for
+// deployments that enable it.
+type MTLSConfig struct {
+	Enabled bool
+
+	// Static cert/key mode, used when UseSPIFFE is false.
+	CertFile   string
+	KeyFile    string
+	CACertFile string // verifies the upstream's server certificate
+
+	// SPIFFE Workload API mode - fetches and auto-rotates both the client
+	// SVID and the trust bundle instead of reading static files.
+	UseSPIFFE        bool
+	SPIFFESocketPath string // defaults to the SPIFFE_ENDPOINT_SOCKET env var when empty
+}
+
+// MTLSSource supplies the client-side tls.Config ServiceProxy dials
+// upstreams with, and reports the expiry of whichever certificate is
+// currently active (surfaced at /debug/certs). Close releases the
+// underlying file watcher or Workload API connection.
+type MTLSSource interface {
+	TLSConfig() *tls.Config
+	Expiry() (time.Time, error)
+	Close() error
+}
+
+// NewMTLSSource builds the MTLSSource cfg describes. A nil, nil return means
+// mTLS is disabled and ServiceProxy should dial upstreams over plain HTTP.
+func NewMTLSSource(ctx context.Context, cfg MTLSConfig, log *logger.Logger) (MTLSSource, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.UseSPIFFE {
+		return newSPIFFEMTLSSource(ctx, cfg.SPIFFESocketPath)
+	}
+	return newFileMTLSSource(cfg, log)
+}
+
+// fileMTLSSource loads a client certificate and key from disk and keeps
+// them current by watching both files for changes with fsnotify, so a
+// cert rotated onto disk (e.g. by cert-manager) is picked up without a
+// restart.
+type fileMTLSSource struct {
+	certFile, keyFile string
+	roots             *x509.CertPool
+	cert              atomic.Pointer[tls.Certificate]
+	watcher           *fsnotify.Watcher
+	log               *logger.Logger
+	done              chan struct{}
+}
+
+func newFileMTLSSource(cfg MTLSConfig, log *logger.Logger) (*fileMTLSSource, error) {
+	roots, err := loadCACertPool(cfg.CACertFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fileMTLSSource{
+		certFile: cfg.CertFile,
+		keyFile:  cfg.KeyFile,
+		roots:    roots,
+		log:      log,
+		done:     make(chan struct{}),
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("mtls: failed to create certificate watcher: %w", err)
+	}
+	for _, f := range []string{cfg.CertFile, cfg.KeyFile} {
+		if err := watcher.Add(f); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("mtls: failed to watch %q: %w", f, err)
+		}
+	}
+	s.watcher = watcher
+	go s.watch()
+
+	return s, nil
+}
+
+func (s *fileMTLSSource) reload() error {
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("mtls: failed to load client cert/key: %w", err)
+	}
+	s.cert.Store(&cert)
+	return nil
+}
+
+// watch reloads the certificate whenever either watched file changes.
+// Editors and cert-manager commonly replace the file (rename-over-write)
+// rather than writing in place, so Create is handled alongside Write.
+func (s *fileMTLSSource) watch() {
+	for {
+		select {
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if err := s.reload(); err != nil {
+				s.log.Error("Failed to reload mTLS client certificate", "error", err)
+				continue
+			}
+			s.log.Info("Reloaded mTLS client certificate", "cert_file", s.certFile)
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+			s.log.Error("mTLS certificate watcher error", "error", err)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *fileMTLSSource) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    s.roots,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.cert.Load(), nil
+		},
+	}
+}
+
+func (s *fileMTLSSource) Expiry() (time.Time, error) {
+	cert := s.cert.Load()
+	if cert == nil || len(cert.Certificate) == 0 {
+		return time.Time{}, fmt.Errorf("mtls: no client certificate loaded")
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("mtls: failed to parse client certificate: %w", err)
+		}
+		leaf = parsed
+	}
+	return leaf.NotAfter, nil
+}
+
+func (s *fileMTLSSource) Close() error {
+	close(s.done)
+	return s.watcher.Close()
+}
+
+func loadCACertPool(caCertFile string) (*x509.CertPool, error) {
+	caCert, err := os.ReadFile(caCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("mtls: failed to read CA bundle %q: %w", caCertFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("mtls: failed to parse CA bundle %q", caCertFile)
+	}
+	return pool, nil
+}
+
+// spiffeMTLSSource fetches the client SVID and trust bundle from the SPIFFE
+// Workload API and keeps both current automatically - the X509Source
+// refreshes itself in the background for as long as it's open.
+type spiffeMTLSSource struct {
+	source *workloadapi.X509Source
+}
+
+func newSPIFFEMTLSSource(ctx context.Context, socketPath string) (*spiffeMTLSSource, error) {
+	var opts []workloadapi.X509SourceOption
+	if socketPath != "" {
+		opts = append(opts, workloadapi.WithClientOptions(workloadapi.WithAddr(socketPath)))
+	}
+
+	source, err := workloadapi.NewX509Source(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("mtls: failed to create SPIFFE X509Source: %w", err)
+	}
+	return &spiffeMTLSSource{source: source}, nil
+}
+
+func (s *spiffeMTLSSource) TLSConfig() *tls.Config {
+	// AuthorizeAny trusts any server identity whose certificate chains to
+	// the Workload API's trust bundle - the bundle is already scoped to the
+	// trust domain(s) the SPIRE agent was configured with, so there's no
+	// further allow-list to apply on the gateway's (client) side.
+	return tlsconfig.MTLSClientConfig(s.source, s.source, tlsconfig.AuthorizeAny())
+}
+
+func (s *spiffeMTLSSource) Expiry() (time.Time, error) {
+	svid, err := s.source.GetX509SVID()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("mtls: failed to fetch current SVID: %w", err)
+	}
+	if len(svid.Certificates) == 0 {
+		return time.Time{}, fmt.Errorf("mtls: current SVID has no certificates")
+	}
+	return svid.Certificates[0].NotAfter, nil
+}
+
+func (s *spiffeMTLSSource) Close() error {
+	return s.source.Close()
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"sync"
+
+	"github.com/captcha-platform/gateway/pkg/logger"
+)
+
+// Pool lazily creates and caches one ServiceProxy per upstream URL, so every
+// call site proxying to the same upstream shares a single breaker and
+// Transport instead of each one standing up its own.
+type Pool struct {
+	mu      sync.Mutex
+	log     *logger.Logger
+	mtls    MTLSSource // nil disables mTLS; every proxy the pool creates shares it
+	proxies map[string]*ServiceProxy
+}
+
+// NewPool creates an empty Pool. Every ServiceProxy it creates dials its
+// upstream over mutual TLS using mtls's client certificate and trust bundle
+// when mtls is non-nil, or plain HTTP otherwise.
+func NewPool(log *logger.Logger, mtls MTLSSource) *Pool {
+	return &Pool{
+		log:     log,
+		mtls:    mtls,
+		proxies: make(map[string]*ServiceProxy),
+	}
+}
+
+// Get returns the ServiceProxy for targetURL, creating it on first use.
+func (p *Pool) Get(targetURL string) (*ServiceProxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if sp, ok := p.proxies[targetURL]; ok {
+		return sp, nil
+	}
+
+	sp, err := NewServiceProxy(targetURL, p.log, p.mtls)
+	if err != nil {
+		return nil, err
+	}
+	p.proxies[targetURL] = sp
+	return sp, nil
+}
+
+// All returns every ServiceProxy created so far, e.g. so the caller can
+// start a StartHealthMonitor goroutine for each.
+func (p *Pool) All() []*ServiceProxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	all := make([]*ServiceProxy, 0, len(p.proxies))
+	for _, sp := range p.proxies {
+		all = append(all, sp)
+	}
+	return all
+}
@@ -2,170 +2,351 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httputil"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/captcha-platform/gateway/internal/metrics"
 	"github.com/captcha-platform/gateway/pkg/logger"
 	"github.com/gin-gonic/gin"
+	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-// ServiceProxy handles proxying requests to backend services
+// hopByHopHeaders are stripped before forwarding a request or response, per
+// RFC 7230 section 6.1 - they describe the current connection, not the
+// underlying resource, so passing them to the next hop verbatim would be
+// wrong (and, for Connection/Upgrade, would break the websocket/SSE
+// passthrough below).
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// idempotentMethods are safe to retry after a transport-level failure
+// (the upstream never responded), since replaying them can't double-apply
+// a side effect the way replaying a POST could.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// maxRetryBodyBytes bounds how much of an idempotent request's body
+// ServiceProxy will buffer in order to replay it on retry. Above this size
+// the body is streamed straight through and the request is not retried,
+// since buffering it would defeat the point of streaming.
+const maxRetryBodyBytes = 4 << 20 // 4MiB
+
+// RetryConfig controls ServiceProxy's retries of idempotent requests that
+// fail at the transport level.
+type RetryConfig struct {
+	MaxAttempts int           // including the first attempt; <= 1 disables retries
+	BaseDelay   time.Duration // backoff base, doubled and jittered per attempt
+}
+
+// DefaultRetryConfig is used by NewServiceProxy when none is given.
+var DefaultRetryConfig = RetryConfig{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond}
+
+// ServiceProxy proxies requests to a single upstream, streaming
+// request/response bodies in both directions via net/http/httputil's
+// ReverseProxy. A circuit breaker fails fast with 503 once the upstream
+// looks unhealthy, instead of letting every request pile up against a dead
+// backend waiting out its own timeout.
 type ServiceProxy struct {
-	targetURL  string
-	httpClient *http.Client
-	logger     *logger.Logger
+	targetURL string
+	target    *url.URL
+	transport *http.Transport
+	reverse   *httputil.ReverseProxy
+	breaker   *gobreaker.CircuitBreaker
+	retry     RetryConfig
+	logger    *logger.Logger
+
+	// mtls is nil unless the pool was built with mTLS enabled, in which
+	// case it also backs /debug/certs' expiry reporting for this upstream.
+	mtls MTLSSource
 }
 
-// NewServiceProxy creates a new service proxy
-func NewServiceProxy(targetURL string, logger *logger.Logger) *ServiceProxy {
-	return &ServiceProxy{
+// proxyAttempt carries the error observed by the ReverseProxy's
+// ErrorHandler back out to ProxyRequest's retry loop. The ErrorHandler
+// deliberately writes nothing to the response so a retry can still produce
+// a clean one - it's only reachable before any response bytes have been
+// copied to the client, since ReverseProxy only calls it when the round
+// trip to the upstream itself fails.
+type proxyAttempt struct {
+	err error
+}
+
+type proxyAttemptKey struct{}
+
+// NewServiceProxy creates a ServiceProxy targeting targetURL, with its own
+// tuned Transport and circuit breaker. When mtls is non-nil, the transport
+// dials the upstream over mutual TLS using mtls's client certificate and
+// trust bundle instead of plain HTTP.
+func NewServiceProxy(targetURL string, log *logger.Logger, mtls MTLSSource) (*ServiceProxy, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: invalid target URL %q: %w", targetURL, err)
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 20,
+		IdleConnTimeout:     90 * time.Second,
+		// Bodies stream through unbuffered, so the only timeouts that apply
+		// are per-phase - there is no overall request Timeout the way the
+		// old http.Client-based proxy had one, which let a single caller
+		// on a slow-drip response hold a connection open for 30s max.
+		ResponseHeaderTimeout: 10 * time.Second,
+	}
+	if mtls != nil {
+		transport.TLSClientConfig = mtls.TLSConfig()
+	}
+
+	p := &ServiceProxy{
 		targetURL: targetURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 20,
-				IdleConnTimeout:     90 * time.Second,
-			},
+		target:    target,
+		transport: transport,
+		retry:     DefaultRetryConfig,
+		logger:    log,
+		mtls:      mtls,
+	}
+
+	p.breaker = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        targetURL,
+		MaxRequests: 1,
+		Interval:    time.Minute,
+		Timeout:     10 * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 5
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			log.Warn("Circuit breaker state changed", "upstream", name, "from", from.String(), "to", to.String())
+			metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(to))
+		},
+	})
+
+	p.reverse = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.Host = target.Host
+			stripHopByHopHeaders(req.Header)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			stripHopByHopHeaders(resp.Header)
+			return nil
+		},
+		// otelhttp.NewTransport starts a span for the outbound call and
+		// injects the traceparent/tracestate headers the upstream's own
+		// otelgin middleware picks back up, so a trace started at the
+		// gateway continues unbroken into the auth/captcha service.
+		Transport: otelhttp.NewTransport(transport),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if attempt, ok := r.Context().Value(proxyAttemptKey{}).(*proxyAttempt); ok {
+				attempt.err = err
+			}
 		},
-		logger: logger,
 	}
+
+	return p, nil
 }
 
-// ProxyRequest proxies the request to the target service
+// ProxyRequest proxies the incoming request to path on the upstream,
+// streaming the body in both directions. Idempotent methods are retried
+// with jittered exponential backoff on a transport-level failure; the
+// circuit breaker short-circuits straight to 503 once the upstream has
+// failed enough consecutive times.
 func (p *ServiceProxy) ProxyRequest(c *gin.Context, path string) {
-	// Build target URL
-	targetURL, err := url.Parse(p.targetURL)
-	if err != nil {
-		p.logger.Error("Failed to parse target URL", "error", err, "url", p.targetURL)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "proxy_error",
-			"message": "Failed to proxy request",
-		})
-		return
-	}
-
-	// Set the path
-	targetURL.Path = "/api/v1" + path
-	targetURL.RawQuery = c.Request.URL.RawQuery
+	retryable := idempotentMethods[c.Request.Method]
 
-	// Read request body
 	var bodyBytes []byte
-	if c.Request.Body != nil {
-		bodyBytes, err = io.ReadAll(c.Request.Body)
+	if retryable && c.Request.Body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(io.LimitReader(c.Request.Body, maxRetryBodyBytes+1))
 		if err != nil {
-			p.logger.Error("Failed to read request body", "error", err)
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "bad_request",
-				"message": "Failed to read request body",
-			})
+			p.logger.Error("Failed to buffer request body for retry", "error", err)
+			writeError(c, http.StatusBadRequest, "bad_request", "Failed to read request body")
 			return
 		}
+		if len(bodyBytes) > maxRetryBodyBytes {
+			// Too large to safely buffer for a retry - fall back to a
+			// single streamed attempt instead.
+			retryable = false
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(bodyBytes), c.Request.Body))
+		} else {
+			c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		}
 	}
 
-	// Create proxy request
-	proxyReq, err := http.NewRequestWithContext(
-		c.Request.Context(),
-		c.Request.Method,
-		targetURL.String(),
-		bytes.NewReader(bodyBytes),
-	)
-	if err != nil {
-		p.logger.Error("Failed to create proxy request", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "proxy_error",
-			"message": "Failed to create proxy request",
-		})
-		return
+	attempts := p.retry.MaxAttempts
+	if attempts < 1 || !retryable {
+		attempts = 1
 	}
 
-	// Copy headers
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			proxyReq.Header.Add(key, value)
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			p.logger.Warn("Retrying proxy request", "target", p.targetURL, "path", path, "attempt", attempt+1)
+			time.Sleep(backoff(attempt, p.retry.BaseDelay))
+			c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		}
+
+		req := p.buildRequest(c, path)
+
+		_, err := p.breaker.Execute(func() (interface{}, error) {
+			p.reverse.ServeHTTP(c.Writer, req)
+			if pa, ok := req.Context().Value(proxyAttemptKey{}).(*proxyAttempt); ok && pa.err != nil {
+				return nil, pa.err
+			}
+			return nil, nil
+		})
+
+		if err == nil {
+			return
+		}
+		lastErr = err
+
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			writeError(c, http.StatusServiceUnavailable, "service_unavailable", "Backend service is temporarily unavailable")
+			return
+		}
+
+		if attempt == attempts-1 || !isRetryableError(err) {
+			break
 		}
 	}
 
-	// Forward client IP
-	clientIP := c.ClientIP()
-	if clientIP != "" {
-		proxyReq.Header.Set("X-Forwarded-For", clientIP)
-		proxyReq.Header.Set("X-Real-IP", clientIP)
+	p.logger.Error("Failed to proxy request", "error", lastErr, "target", p.targetURL, "path", path)
+	writeError(c, http.StatusBadGateway, "service_unavailable", "Backend service is unavailable")
+}
+
+// buildRequest clones the inbound request onto path against this proxy's
+// upstream, carrying a fresh proxyAttempt in its context so the
+// ReverseProxy's ErrorHandler can report a transport failure back to the
+// retry loop in ProxyRequest.
+func (p *ServiceProxy) buildRequest(c *gin.Context, path string) *http.Request {
+	ctx := context.WithValue(c.Request.Context(), proxyAttemptKey{}, &proxyAttempt{})
+	req := c.Request.Clone(ctx)
+
+	req.URL.Path = "/api/v1" + path
+	req.URL.RawQuery = c.Request.URL.RawQuery
+	req.RequestURI = ""
+
+	// Record which upstream this request was routed to, so
+	// middleware.AuditLog can include it in the request's audit record.
+	c.Set("audit_upstream", p.targetURL)
+
+	// Forward client IP, overwriting rather than appending since the
+	// gateway is the first hop the upstream should trust.
+	if clientIP := c.ClientIP(); clientIP != "" {
+		req.Header.Set("X-Forwarded-For", clientIP)
+		req.Header.Set("X-Real-IP", clientIP)
 	}
 
-	// Forward request ID
 	if requestID := c.GetString("request_id"); requestID != "" {
-		proxyReq.Header.Set("X-Request-ID", requestID)
+		req.Header.Set("X-Request-ID", requestID)
 	}
-
-	// Forward user info if authenticated
 	if userID, exists := c.Get("user_id"); exists {
-		proxyReq.Header.Set("X-User-ID", fmt.Sprintf("%v", userID))
+		req.Header.Set("X-User-ID", fmt.Sprintf("%v", userID))
 	}
 	if userEmail, exists := c.Get("user_email"); exists {
-		proxyReq.Header.Set("X-User-Email", userEmail.(string))
+		req.Header.Set("X-User-Email", userEmail.(string))
 	}
 	if userRole, exists := c.Get("user_role"); exists {
-		proxyReq.Header.Set("X-User-Role", userRole.(string))
+		req.Header.Set("X-User-Role", userRole.(string))
 	}
-
-	// Forward API key hash if present
 	if apiKeyHash, exists := c.Get("api_key_hash"); exists {
-		proxyReq.Header.Set("X-API-Key-Hash", apiKeyHash.(string))
+		req.Header.Set("X-API-Key-Hash", apiKeyHash.(string))
 	}
 
-	// Execute request
-	p.logger.Debug("Proxying request",
-		"method", c.Request.Method,
-		"path", path,
-		"target", targetURL.String(),
-	)
+	return req
+}
 
-	resp, err := p.httpClient.Do(proxyReq)
-	if err != nil {
-		p.logger.Error("Failed to proxy request", "error", err, "target", targetURL.String())
-		c.JSON(http.StatusBadGateway, gin.H{
-			"error":   "service_unavailable",
-			"message": "Backend service is unavailable",
-		})
-		return
+// stripHopByHopHeaders removes the fixed RFC 7230 hop-by-hop headers plus
+// anything the Connection header names, in place.
+func stripHopByHopHeaders(h http.Header) {
+	if connection := h.Get("Connection"); connection != "" {
+		for _, name := range strings.Split(connection, ",") {
+			h.Del(strings.TrimSpace(name))
+		}
 	}
-	defer resp.Body.Close()
+	for _, name := range hopByHopHeaders {
+		h.Del(name)
+	}
+}
 
-	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		p.logger.Error("Failed to read response body", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "proxy_error",
-			"message": "Failed to read response",
-		})
-		return
+// isRetryableError reports whether err looks like a transport-level
+// failure (connection refused, reset, timeout) rather than something the
+// upstream is unlikely to answer differently to on a retry.
+func isRetryableError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
 	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
 
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			c.Header(key, value)
-		}
+// backoff returns a jittered exponential delay for the given attempt
+// (0-indexed) based on base.
+func backoff(attempt int, base time.Duration) time.Duration {
+	if base <= 0 {
+		base = 50 * time.Millisecond
 	}
+	d := base << uint(attempt-1)
+	jitter := time.Duration(rand.Int63n(int64(d) + 1))
+	return d + jitter
+}
 
-	// Write response
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+func writeError(c *gin.Context, status int, errCode, message string) {
+	c.JSON(status, gin.H{"error": errCode, "message": message})
 }
 
-// HealthCheck checks if the target service is healthy
-func (p *ServiceProxy) HealthCheck() error {
-	targetURL, err := url.Parse(p.targetURL)
-	if err != nil {
-		return err
+// TargetURL returns the upstream URL this proxy forwards to.
+func (p *ServiceProxy) TargetURL() string {
+	return p.targetURL
+}
+
+// CertExpiry reports the NotAfter of this proxy's client certificate, for
+// the /debug/certs endpoint. ok is false when this upstream wasn't
+// configured for mTLS.
+func (p *ServiceProxy) CertExpiry() (expiry time.Time, ok bool, err error) {
+	if p.mtls == nil {
+		return time.Time{}, false, nil
 	}
-	targetURL.Path = "/health"
+	expiry, err = p.mtls.Expiry()
+	return expiry, true, err
+}
 
-	resp, err := p.httpClient.Get(targetURL.String())
+// HealthCheck reports whether the upstream's /livez endpoint is
+// reachable and returning 200, used by StartHealthMonitor to probe a
+// tripped circuit breaker back closed.
+func (p *ServiceProxy) HealthCheck() error {
+	targetURL := *p.target
+	targetURL.Path = "/livez"
+
+	client := &http.Client{Timeout: 5 * time.Second, Transport: p.transport}
+	resp, err := client.Get(targetURL.String())
 	if err != nil {
 		return err
 	}
@@ -174,6 +355,32 @@ func (p *ServiceProxy) HealthCheck() error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("health check failed with status: %d", resp.StatusCode)
 	}
-
 	return nil
-}
\ No newline at end of file
+}
+
+// StartHealthMonitor periodically runs HealthCheck through the circuit
+// breaker until ctx is done. This is what lets a tripped breaker recover
+// automatically: gobreaker only lets a request through a tripped breaker
+// once its Timeout has elapsed (moving it to half-open), and a successful
+// call there closes it again - running HealthCheck on a schedule means
+// that probe happens on its own instead of waiting for the next real
+// request to stumble into the half-open window.
+func (p *ServiceProxy) StartHealthMonitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = p.breaker.Execute(func() (interface{}, error) {
+				return nil, p.HealthCheck()
+			})
+		}
+	}
+}
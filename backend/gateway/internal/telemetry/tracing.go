@@ -0,0 +1,62 @@
+// Package telemetry wires up OpenTelemetry tracing for the gateway. Export
+// is opt-in: with no OTEL_EXPORTER_OTLP_ENDPOINT configured, Init leaves the
+// global no-op tracer provider in place so otelgin/otelhttp spans are
+// created and propagated (traceparent/tracestate headers still flow through
+// the proxy) but never leave the process.
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/captcha-platform/gateway/pkg/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// Shutdown flushes and stops whatever tracer provider Init installed. It's
+// always safe to call, even when Init never configured a real exporter.
+type Shutdown func(ctx context.Context) error
+
+// Init configures global tracing for serviceName. otlpEndpoint is the
+// OTEL_EXPORTER_OTLP_ENDPOINT value (e.g. "otel-collector:4318"); when
+// empty, Init registers the global propagator only and returns a no-op
+// Shutdown.
+func Init(serviceName, otlpEndpoint string, log *logger.Logger) Shutdown {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if otlpEndpoint == "" {
+		return func(context.Context) error { return nil }
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(otlpEndpoint), otlptracehttp.WithInsecure())
+	if err != nil {
+		log.Error("Failed to configure OTLP trace exporter, tracing will be local-only", "error", err)
+		return func(context.Context) error { return nil }
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)))
+	if err != nil {
+		log.Error("Failed to build OTel resource", "error", err)
+		res = resource.Default()
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown
+}
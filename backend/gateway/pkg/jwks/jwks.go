@@ -0,0 +1,181 @@
+// Package jwks fetches and caches a JSON Web Key Set so the gateway can
+// verify RS256/ES256-signed access tokens published by the auth service,
+// without sharing its HS256 secret.
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwk mirrors the fields the auth service publishes for its signing keys
+// (see the auth service's pkg/jwt.JWK): RSA keys carry n/e, EC keys carry
+// crv/x/y.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwksDocument struct {
+	Keys []jwk `json:"keys"`
+}
+
+// Client fetches and caches the auth service's JWKS document. Keys are kept
+// as crypto.PublicKey (either *rsa.PublicKey or *ecdsa.PublicKey) so callers
+// can verify both RS256 and ES256 tokens from the same cache.
+type Client struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// NewClient creates a Client that fetches the JWKS from url, caching the
+// result for ttl before refetching.
+func NewClient(url string, ttl time.Duration) *Client {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	return &Client{
+		url:        url,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       make(map[string]interface{}),
+	}
+}
+
+// PublicKey returns the public key for kid, either a *rsa.PublicKey or an
+// *ecdsa.PublicKey depending on how the auth service generated it. The
+// cached JWKS is refreshed first if it's stale or doesn't yet contain kid,
+// so a just-rotated signing key is picked up without waiting out the full
+// TTL.
+func (c *Client) PublicKey(kid string) (interface{}, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) >= c.ttl
+	c.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := c.refresh(); err != nil {
+		if ok {
+			// Serve the stale key rather than fail outright on a transient
+			// fetch error.
+			return key, nil
+		}
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key, ok = c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("jwks: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (c *Client) refresh() error {
+	resp, err := c.httpClient.Get(c.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks: unexpected status %d fetching %s", resp.StatusCode, c.url)
+	}
+
+	var doc jwksDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return err
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		pub, err := toPublicKey(k)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+
+	return nil
+}
+
+func toPublicKey(k jwk) (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		return toRSAPublicKey(k)
+	case "EC":
+		return toECPublicKey(k)
+	default:
+		return nil, fmt.Errorf("jwks: unsupported key type %q", k.Kty)
+	}
+}
+
+func toRSAPublicKey(k jwk) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+func toECPublicKey(k jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	default:
+		return nil, fmt.Errorf("jwks: unsupported EC curve %q", k.Crv)
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}, nil
+}
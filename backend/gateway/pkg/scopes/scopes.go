@@ -0,0 +1,53 @@
+// Package scopes defines the canonical API-key permission vocabulary and
+// the hierarchical/wildcard matching rules used to enforce it.
+package scopes
+
+import "strings"
+
+// Canonical lists every scope an API key can be granted. Any other value is
+// rejected by Valid.
+var Canonical = []string{
+	"captcha:solve",
+	"captcha:solve:batch",
+	"models:read",
+	"models:write",
+	"train:write",
+	"stats:read",
+	"logs:read",
+	"admin:*",
+}
+
+// Valid reports whether scope is part of the canonical vocabulary.
+func Valid(scope string) bool {
+	for _, c := range Canonical {
+		if c == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Grants reports whether granted (a scope an API key actually holds) covers
+// required (the scope a route demands). Scopes only nest through an explicit
+// "prefix:*" wildcard (e.g. "admin:*" grants "admin:users:write"); a plain
+// scope like "captcha:solve" grants only itself, not sibling leaf scopes
+// such as "captcha:solve:batch".
+func Grants(granted, required string) bool {
+	if granted == required {
+		return true
+	}
+	if strings.HasSuffix(granted, ":*") {
+		return strings.HasPrefix(required, strings.TrimSuffix(granted, "*"))
+	}
+	return false
+}
+
+// AnyGrants reports whether any of the caller's granted scopes covers required.
+func AnyGrants(granted []string, required string) bool {
+	for _, g := range granted {
+		if Grants(g, required) {
+			return true
+		}
+	}
+	return false
+}